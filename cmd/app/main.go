@@ -1,36 +1,180 @@
 package main
 
 import (
+	"context"
+	"flag"
+	"fmt"
 	"log"
-	"net/http"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 	"vilow-be/config"
-
-	"github.com/joho/godotenv"
+	"vilow-be/pkg/messaging"
+	"vilow-be/pkg/playback"
+	"vilow-be/pkg/search"
+	"vilow-be/pkg/timeline"
+	"vilow-be/pkg/trending"
+	"vilow-be/pkg/utils"
 )
 
-var PORT = ":8080"
-
 func main() {
-	if err := godotenv.Load(); err != nil {
-		log.Fatalf("Error loading .env file: %v", err)
+	configPath := flag.String("config", "", "path to a YAML configuration file")
+	printConfig := flag.Bool("print-config", false, "print the resolved configuration with secrets redacted and exit")
+	flag.Parse()
+
+	cfg, err := config.Load(*configPath)
+	if err != nil {
+		log.Fatalf("Invalid configuration:\n%v", err)
+	}
+
+	if *printConfig {
+		if err := cfg.Print(os.Stdout); err != nil {
+			log.Fatalf("Error printing configuration: %v", err)
+		}
+		return
+	}
+
+	logger, err := config.SetupLogger(cfg)
+	if err != nil {
+		log.Fatalf("Error setting up logger: %v", err)
+	}
+
+	if err := run(cfg, logger); err != nil {
+		logger.Error("Server failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+// run starts the server and blocks until SIGINT or SIGTERM. It then stops
+// in order: the listener and in-flight requests first, the background
+// workers that requests feed next, and the clients they all use last.
+func run(cfg *config.Config, logger *slog.Logger) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Registered first so it runs last and keeps the spans of the shutdown.
+	shutdownTracing, err := config.SetupTracing(ctx, cfg)
+	if err != nil {
+		return fmt.Errorf("setting up tracing: %w", err)
 	}
 
-	client, err := config.SetupDatabase()
+	defer func() {
+		flushCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+		defer cancel()
+
+		if err := shutdownTracing(flushCtx); err != nil {
+			logger.Error("Error flushing traces", "error", err)
+		}
+	}()
+
+	client, err := config.SetupDatabase(cfg)
 	if err != nil {
-		log.Fatalf("Error setting up database: %v", err)
+		return fmt.Errorf("setting up database: %w", err)
 	}
 
 	defer func() {
-		_ = client.Prisma.Disconnect()
+		if err := client.Prisma.Disconnect(); err != nil {
+			logger.Error("Error disconnecting from database", "error", err)
+		}
 	}()
 
-	minioClient, err := config.SetupMinio()
+	// The MinIO client only holds pooled HTTP connections, there is nothing
+	// to close once the requests using it are done.
+	minioClient, err := config.SetupMinio(cfg)
 	if err != nil {
-		log.Fatalf("Error setting up MinIO: %v", err)
+		return fmt.Errorf("setting up MinIO: %w", err)
+	}
+
+	searchIndex := search.NewMemoryIndex(nil)
+	if err := utils.BuildSearchIndex(ctx, client, searchIndex); err != nil {
+		return fmt.Errorf("building search index: %w", err)
 	}
 
-	corsHandler := config.SetupServer(client, minioClient)
+	checker := config.SetupHealth(cfg, client, minioClient)
+
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	defer stopWorkers()
+
+	var workers sync.WaitGroup
+	startWorker := func(name string, run func(context.Context)) {
+		stopped := checker.Worker(name)
+		workers.Add(1)
+		go func() {
+			defer workers.Done()
+			defer stopped()
+			run(workersCtx)
+		}()
+	}
+
+	timelines := timeline.NewService(client, timeline.DefaultFanoutLimit, timeline.DefaultBackfill)
+	startWorker("timeline", timelines.Run)
+
+	trends := trending.NewAggregator(client, trending.DefaultWeights, trending.DefaultRefreshInterval)
+	startWorker("trending", trends.Run)
+
+	tracker := playback.NewTracker(client, playback.DefaultViewWindow, playback.DefaultFlushInterval)
+	startWorker("playback", tracker.Run)
+
+	hub := messaging.NewHub()
+
+	handler, err := config.SetupServer(cfg, client, minioClient, searchIndex, timelines, trends, tracker, hub, checker, logger)
+	if err != nil {
+		return fmt.Errorf("setting up routes: %w", err)
+	}
+
+	server := config.NewHTTPServer(cfg, handler)
+	// Event streams never finish on their own, closing the hub ends them.
+	server.RegisterOnShutdown(hub.Close)
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- config.Serve(cfg, server)
+	}()
+
+	logger.Info("Server running", "port", cfg.Server.Port, "tls", cfg.Server.TLS.Enabled())
+
+	select {
+	case err := <-serveErr:
+		stopWorkers()
+		workers.Wait()
+		return fmt.Errorf("running server: %w", err)
+	case <-ctx.Done():
+	}
+
+	// A second signal kills the process right away.
+	stop()
+
+	checker.SetShuttingDown()
+	if cfg.Server.ShutdownDelay > 0 {
+		logger.Info("Reporting not ready before shutting down", "delay", cfg.Server.ShutdownDelay.String())
+		time.Sleep(cfg.Server.ShutdownDelay)
+	}
+	logger.Info("Shutting down, waiting for requests and workers to finish", "timeout", cfg.Server.ShutdownTimeout.String())
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		logger.Error("Error draining requests", "error", err)
+		_ = server.Close()
+	}
+
+	stopWorkers()
+	drained := make(chan struct{})
+	go func() {
+		workers.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-shutdownCtx.Done():
+		logger.Error("Background workers did not stop in time", "error", shutdownCtx.Err())
+	}
 
-	log.Printf("Server running on port %s", PORT)
-	log.Fatal(http.ListenAndServe(PORT, corsHandler))
+	logger.Info("Server stopped")
+	return nil
 }
\ No newline at end of file
@@ -0,0 +1,372 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/tracing"
+
+	"github.com/joho/godotenv"
+	"gopkg.in/yaml.v3"
+)
+
+const redacted = "<redacted>"
+
+// Config holds every setting of the server. It is loaded once at startup
+// by Load and handed to whatever needs it.
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Minio    MinioConfig    `yaml:"minio"`
+	Auth     AuthConfig     `yaml:"auth"`
+	CORS     CORSConfig     `yaml:"cors"`
+	Log      LogConfig      `yaml:"log"`
+	Tracing  TracingConfig  `yaml:"tracing"`
+}
+
+type ServerConfig struct {
+	Port int `yaml:"port"`
+
+	// ReadHeaderTimeout bounds how long a client may take to send the
+	// request headers, ReadTimeout and WriteTimeout the whole request and
+	// response. Uploads have to fit in both.
+	ReadHeaderTimeout time.Duration `yaml:"readHeaderTimeout"`
+	ReadTimeout       time.Duration `yaml:"readTimeout"`
+	WriteTimeout      time.Duration `yaml:"writeTimeout"`
+	IdleTimeout       time.Duration `yaml:"idleTimeout"`
+	// ShutdownTimeout is how long in-flight requests and background workers
+	// get to finish once the server is asked to stop.
+	ShutdownTimeout time.Duration `yaml:"shutdownTimeout"`
+	// ShutdownDelay is how long /readyz reports the server as degraded
+	// before it stops accepting connections, so load balancers notice.
+	ShutdownDelay time.Duration `yaml:"shutdownDelay"`
+
+	TLS TLSConfig `yaml:"tls"`
+}
+
+// Addr is the address the HTTP server listens on.
+func (c ServerConfig) Addr() string {
+	return fmt.Sprintf(":%d", c.Port)
+}
+
+// TLSConfig turns on HTTPS when both files are set. HTTP/2 is negotiated
+// over TLS unless HTTP2 is false.
+type TLSConfig struct {
+	CertFile string `yaml:"certFile"`
+	KeyFile  string `yaml:"keyFile"`
+	HTTP2    bool   `yaml:"http2"`
+}
+
+// Enabled reports whether the server should serve HTTPS.
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+type DatabaseConfig struct {
+	URL string `yaml:"url"`
+}
+
+type MinioConfig struct {
+	Endpoint  string `yaml:"endpoint"`
+	AccessKey string `yaml:"accessKey"`
+	SecretKey string `yaml:"secretKey"`
+	Bucket    string `yaml:"bucket"`
+	UseSSL    bool   `yaml:"useSSL"`
+}
+
+type AuthConfig struct {
+	JWTSecret string        `yaml:"jwtSecret"`
+	TokenTTL  time.Duration `yaml:"tokenTTL"`
+}
+
+type CORSConfig struct {
+	AllowedOrigins []string `yaml:"allowedOrigins"`
+}
+
+// LogConfig picks the level, one of debug, info, warn or error, and the
+// format, json or text, of the server logs.
+type LogConfig struct {
+	Level  string `yaml:"level"`
+	Format string `yaml:"format"`
+}
+
+// TracingConfig picks where OpenTelemetry spans go: nowhere, stdout, a
+// file or an OTLP/HTTP collector.
+type TracingConfig struct {
+	Exporter    string  `yaml:"exporter"`
+	ServiceName string  `yaml:"serviceName"`
+	SampleRatio float64 `yaml:"sampleRatio"`
+	File        string  `yaml:"file"`
+	Endpoint    string  `yaml:"endpoint"`
+	Insecure    bool    `yaml:"insecure"`
+}
+
+// Default returns the settings used for anything the YAML file and the
+// environment leave out.
+func Default() *Config {
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       5 * time.Minute,
+			WriteTimeout:      5 * time.Minute,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   30 * time.Second,
+			TLS: TLSConfig{
+				HTTP2: true,
+			},
+		},
+		Auth: AuthConfig{
+			TokenTTL: 5 * time.Hour,
+		},
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:5173"},
+		},
+		Log: LogConfig{
+			Level:  "info",
+			Format: logging.FormatJSON,
+		},
+		Tracing: TracingConfig{
+			Exporter:    tracing.ExporterNone,
+			ServiceName: "vilow-be",
+			SampleRatio: 1,
+			File:        "traces.json",
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the optional YAML file
+// at path and the environment, each one overriding the previous. A .env
+// file in the working directory is read into the environment first, without
+// replacing variables that are already set.
+func Load(path string) (*Config, error) {
+	if err := godotenv.Load(); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("reading .env: %w", err)
+	}
+
+	cfg := Default()
+
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading config file: %w", err)
+		}
+
+		decoder := yaml.NewDecoder(bytes.NewReader(data))
+		decoder.KnownFields(true)
+		if err := decoder.Decode(cfg); err != nil && !errors.Is(err, io.EOF) {
+			return nil, fmt.Errorf("parsing config file %s: %w", path, err)
+		}
+	}
+
+	if err := cfg.applyEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// Validate reports every missing or malformed setting at once, so a broken
+// deployment can be fixed in one go.
+func (c *Config) Validate() error {
+	var errs []error
+	required := func(value, name, env string) {
+		if strings.TrimSpace(value) == "" {
+			errs = append(errs, fmt.Errorf("%s is required (set %s)", name, env))
+		}
+	}
+	positive := func(value time.Duration, name string) {
+		if value <= 0 {
+			errs = append(errs, fmt.Errorf("%s must be positive, got %s", name, value))
+		}
+	}
+
+	if c.Server.Port <= 0 || c.Server.Port > 65535 {
+		errs = append(errs, fmt.Errorf("server.port must be between 1 and 65535, got %d", c.Server.Port))
+	}
+
+	positive(c.Server.ReadHeaderTimeout, "server.readHeaderTimeout")
+	positive(c.Server.ReadTimeout, "server.readTimeout")
+	positive(c.Server.WriteTimeout, "server.writeTimeout")
+	positive(c.Server.IdleTimeout, "server.idleTimeout")
+	positive(c.Server.ShutdownTimeout, "server.shutdownTimeout")
+	if c.Server.ShutdownDelay < 0 {
+		errs = append(errs, fmt.Errorf("server.shutdownDelay cannot be negative, got %s", c.Server.ShutdownDelay))
+	}
+
+	if (c.Server.TLS.CertFile == "") != (c.Server.TLS.KeyFile == "") {
+		errs = append(errs, errors.New("server.tls.certFile and server.tls.keyFile must be set together (set TLS_CERT_FILE and TLS_KEY_FILE)"))
+	}
+
+	required(c.Database.URL, "database.url", "DATABASE_URL")
+	required(c.Minio.Endpoint, "minio.endpoint", "MINIO_ENDPOINT_URL")
+	required(c.Minio.AccessKey, "minio.accessKey", "MINIO_ROOT_USER")
+	required(c.Minio.SecretKey, "minio.secretKey", "MINIO_ROOT_PASSWORD")
+	required(c.Minio.Bucket, "minio.bucket", "BUCKET_NAME")
+	required(c.Auth.JWTSecret, "auth.jwtSecret", "JWT_SECRET_KEY")
+
+	positive(c.Auth.TokenTTL, "auth.tokenTTL")
+
+	if len(c.CORS.AllowedOrigins) == 0 {
+		errs = append(errs, errors.New("cors.allowedOrigins needs at least one origin (set CORS_ALLOWED_ORIGINS)"))
+	}
+	for _, origin := range c.CORS.AllowedOrigins {
+		if origin == "*" {
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("cors.allowedOrigins: %q is not an origin like https://example.com", origin))
+		}
+	}
+
+	if _, err := logging.New(io.Discard, c.Log.Level, c.Log.Format); err != nil {
+		errs = append(errs, fmt.Errorf("log: %w (set LOG_LEVEL and LOG_FORMAT)", err))
+	}
+
+	if !tracing.ValidExporter(c.Tracing.Exporter) {
+		errs = append(errs, fmt.Errorf("tracing.exporter must be one of none, stdout, file or otlp, got %q (set TRACING_EXPORTER)", c.Tracing.Exporter))
+	}
+	if c.Tracing.SampleRatio < 0 || c.Tracing.SampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("tracing.sampleRatio must be between 0 and 1, got %g", c.Tracing.SampleRatio))
+	}
+	if c.Tracing.Exporter == tracing.ExporterFile {
+		required(c.Tracing.File, "tracing.file", "TRACING_FILE")
+	}
+	required(c.Tracing.ServiceName, "tracing.serviceName", "TRACING_SERVICE_NAME")
+
+	return errors.Join(errs...)
+}
+
+// Redacted returns a copy of the configuration that is safe to print.
+func (c *Config) Redacted() *Config {
+	copied := *c
+	copied.CORS.AllowedOrigins = append([]string{}, c.CORS.AllowedOrigins...)
+
+	if u, err := url.Parse(c.Database.URL); err == nil && u.User != nil {
+		copied.Database.URL = u.Redacted()
+	} else if c.Database.URL != "" {
+		copied.Database.URL = redacted
+	}
+
+	for _, secret := range []*string{&copied.Minio.SecretKey, &copied.Auth.JWTSecret} {
+		if *secret != "" {
+			*secret = redacted
+		}
+	}
+
+	return &copied
+}
+
+// Print writes the configuration as YAML with its secrets redacted.
+func (c *Config) Print(w io.Writer) error {
+	encoder := yaml.NewEncoder(w)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(c.Redacted()); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+func (c *Config) applyEnv() error {
+	var errs []error
+
+	lookupString := func(name string, target *string) {
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			*target = value
+		}
+	}
+
+	lookup := func(name string, parse func(string) error) {
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			if err := parse(strings.TrimSpace(value)); err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", name, err))
+			}
+		}
+	}
+
+	lookup("PORT", func(value string) (err error) {
+		c.Server.Port, err = strconv.Atoi(strings.TrimPrefix(value, ":"))
+		return err
+	})
+
+	duration := func(target *time.Duration) func(string) error {
+		return func(value string) (err error) {
+			*target, err = time.ParseDuration(value)
+			return err
+		}
+	}
+
+	lookup("SERVER_READ_HEADER_TIMEOUT", duration(&c.Server.ReadHeaderTimeout))
+	lookup("SERVER_READ_TIMEOUT", duration(&c.Server.ReadTimeout))
+	lookup("SERVER_WRITE_TIMEOUT", duration(&c.Server.WriteTimeout))
+	lookup("SERVER_IDLE_TIMEOUT", duration(&c.Server.IdleTimeout))
+	lookup("SERVER_SHUTDOWN_TIMEOUT", duration(&c.Server.ShutdownTimeout))
+	lookup("SERVER_SHUTDOWN_DELAY", duration(&c.Server.ShutdownDelay))
+
+	lookupString("TLS_CERT_FILE", &c.Server.TLS.CertFile)
+	lookupString("TLS_KEY_FILE", &c.Server.TLS.KeyFile)
+	lookup("HTTP2_ENABLED", func(value string) (err error) {
+		c.Server.TLS.HTTP2, err = strconv.ParseBool(value)
+		return err
+	})
+
+	lookupString("DATABASE_URL", &c.Database.URL)
+
+	lookupString("MINIO_ENDPOINT_URL", &c.Minio.Endpoint)
+	lookupString("MINIO_ROOT_USER", &c.Minio.AccessKey)
+	lookupString("MINIO_ROOT_PASSWORD", &c.Minio.SecretKey)
+	lookupString("BUCKET_NAME", &c.Minio.Bucket)
+	lookup("MINIO_USE_SSL", func(value string) (err error) {
+		c.Minio.UseSSL, err = strconv.ParseBool(value)
+		return err
+	})
+
+	lookupString("JWT_SECRET_KEY", &c.Auth.JWTSecret)
+	lookup("JWT_TOKEN_TTL", duration(&c.Auth.TokenTTL))
+
+	lookupString("LOG_LEVEL", &c.Log.Level)
+	lookupString("LOG_FORMAT", &c.Log.Format)
+
+	lookupString("TRACING_EXPORTER", &c.Tracing.Exporter)
+	lookupString("TRACING_SERVICE_NAME", &c.Tracing.ServiceName)
+	lookup("TRACING_SAMPLE_RATIO", func(value string) (err error) {
+		c.Tracing.SampleRatio, err = strconv.ParseFloat(value, 64)
+		return err
+	})
+	lookupString("TRACING_FILE", &c.Tracing.File)
+	lookupString("TRACING_ENDPOINT", &c.Tracing.Endpoint)
+	lookup("TRACING_INSECURE", func(value string) (err error) {
+		c.Tracing.Insecure, err = strconv.ParseBool(value)
+		return err
+	})
+
+	lookup("CORS_ALLOWED_ORIGINS", func(value string) error {
+		c.CORS.AllowedOrigins = splitList(value)
+		return nil
+	})
+
+	return errors.Join(errs...)
+}
+
+func splitList(value string) []string {
+	items := []string{}
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+
+	return items
+}
@@ -1,12 +1,22 @@
 package config
 
 import (
+	"os"
+	"vilow-be/pkg/metrics"
+	"vilow-be/pkg/tracing"
 	"vilow-be/prisma/db"
 )
 
 // SetupDatabase is a function that sets up the database client
-func SetupDatabase() (*db.PrismaClient, error) {
+func SetupDatabase(cfg *Config) (*db.PrismaClient, error) {
+	// The query engine reads its connection string from the environment, so
+	// a URL that came from the config file has to be exported first.
+	if err := os.Setenv("DATABASE_URL", cfg.Database.URL); err != nil {
+		return nil, err
+	}
+
 	client := db.NewClient()
+	client.Engine = tracing.InstrumentEngine(metrics.InstrumentEngine(client.Engine))
 	if err := client.Prisma.Connect(); err != nil {
 		return nil, err
 	}
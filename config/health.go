@@ -0,0 +1,38 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"vilow-be/pkg/health"
+	"vilow-be/prisma/db"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// SetupHealth is a function that sets up the readiness checks of the
+// database and the storage bucket. Background workers register themselves.
+func SetupHealth(cfg *Config, client *db.PrismaClient, minioClient *minio.Client) *health.Checker {
+	checker := health.NewChecker(health.DefaultTimeout)
+
+	checker.Register("database", func(ctx context.Context) error {
+		_, err := client.User.FindFirst().Exec(ctx)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+		return nil
+	})
+
+	checker.Register("storage", func(ctx context.Context) error {
+		exists, err := minioClient.BucketExists(ctx, cfg.Minio.Bucket)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fmt.Errorf("bucket %q does not exist", cfg.Minio.Bucket)
+		}
+		return nil
+	})
+
+	return checker
+}
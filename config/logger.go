@@ -0,0 +1,19 @@
+package config
+
+import (
+	"log/slog"
+	"os"
+	"vilow-be/pkg/logging"
+)
+
+// SetupLogger is a function that sets up the server logger and makes it the
+// default, so the standard log package writes through it as well.
+func SetupLogger(cfg *Config) (*slog.Logger, error) {
+	logger, err := logging.New(os.Stdout, cfg.Log.Level, cfg.Log.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	slog.SetDefault(logger)
+	return logger, nil
+}
@@ -1,22 +1,24 @@
 package config
 
 import (
-	"os"
+	"vilow-be/pkg/metrics"
+	"vilow-be/pkg/tracing"
 
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
 )
 
 // SetupMinio is a function that sets up the MinIO client
-func SetupMinio() (*minio.Client, error) {
-	minioEndpoint := os.Getenv("MINIO_ENDPOINT_URL")
-	minioAccessKey := os.Getenv("MINIO_ROOT_USER")
-	minioSecretKey := os.Getenv("MINIO_ROOT_PASSWORD")
-	useSSL := false
+func SetupMinio(cfg *Config) (*minio.Client, error) {
+	transport, err := minio.DefaultTransport(cfg.Minio.UseSSL)
+	if err != nil {
+		return nil, err
+	}
 
-	minioClient, err := minio.New(minioEndpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(minioAccessKey, minioSecretKey, ""),
-		Secure: useSSL,
+	minioClient, err := minio.New(cfg.Minio.Endpoint, &minio.Options{
+		Creds:     credentials.NewStaticV4(cfg.Minio.AccessKey, cfg.Minio.SecretKey, ""),
+		Secure:    cfg.Minio.UseSSL,
+		Transport: tracing.InstrumentTransport(metrics.InstrumentTransport(transport)),
 	})
 	if err != nil {
 		return nil, err
@@ -1,52 +1,223 @@
 package config
 
 import (
+	"crypto/tls"
+	"errors"
+	"log/slog"
 	"net/http"
 	"vilow-be/pkg/handlers"
+	"vilow-be/pkg/health"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/messaging"
+	"vilow-be/pkg/metrics"
 	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/models"
+	"vilow-be/pkg/openapi"
+	"vilow-be/pkg/playback"
+	"vilow-be/pkg/search"
+	"vilow-be/pkg/timeline"
+	"vilow-be/pkg/trending"
 	"vilow-be/prisma/db"
 
 	"github.com/gorilla/mux"
 	"github.com/minio/minio-go/v7"
 	"github.com/rs/cors"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gorilla/mux/otelmux"
 )
 
 // SetupServer is a function that sets up the server
-func SetupServer(client *db.PrismaClient, minioClient *minio.Client) http.Handler {
-	r := mux.NewRouter()
-
+func SetupServer(cfg *Config, client *db.PrismaClient, minioClient *minio.Client, index search.Index, timelines *timeline.Service, trends *trending.Aggregator, tracker *playback.Tracker, hub *messaging.Hub, checker *health.Checker, logger *slog.Logger) (http.Handler, error) {
 	c := cors.New(cors.Options{
-		AllowedOrigins:   []string{"http://localhost:5173"},
+		AllowedOrigins:   cfg.CORS.AllowedOrigins,
 		AllowCredentials: true,
+		ExposedHeaders:   []string{logging.RequestIDHeader},
 	})
 
 	// r.Use(middleware.CorsMiddleware)
+	r, err := NewRouter(cfg, client, minioClient, index, timelines, trends, tracker, hub, checker, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.Handler(r), nil
+}
+
+// NewRouter registers every route of the API. Each of them must be described
+// in the OpenAPI document, which the tests check.
+func NewRouter(cfg *Config, client *db.PrismaClient, minioClient *minio.Client, index search.Index, timelines *timeline.Service, trends *trending.Aggregator, tracker *playback.Tracker, hub *messaging.Hub, checker *health.Checker, logger *slog.Logger) (*mux.Router, error) {
+	spec, err := openapi.New()
+	if err != nil {
+		return nil, err
+	}
+
+	r := mux.NewRouter()
+
+	r.NotFoundHandler = handlers.NotFoundHandler()
+	r.MethodNotAllowedHandler = handlers.MethodNotAllowedHandler()
+	r.Use(otelmux.Middleware(cfg.Tracing.ServiceName), logging.Middleware(logger), metrics.Middleware)
+
+	// Health routes, probed by the orchestrator
+	r.HandleFunc("/healthz", handlers.HealthzHandler()).Methods(http.MethodGet)
+	r.HandleFunc("/readyz", handlers.ReadyzHandler(checker)).Methods(http.MethodGet)
+	r.Handle("/metrics", metrics.Handler()).Methods(http.MethodGet)
+
+	// API documentation
+	r.Handle("/openapi.json", spec.Handler()).Methods(http.MethodGet)
+	r.Handle("/docs", openapi.DocsHandler()).Methods(http.MethodGet)
 
 	// Public routes
 	// User public routes
-	r.HandleFunc("/user", handlers.CreateUserHandler(client)).Methods(http.MethodPost)
+	r.HandleFunc("/user", handlers.CreateUserHandler(client, index)).Methods(http.MethodPost)
 
 	// Auth public routes
-	r.HandleFunc("/login", handlers.AuthHandler(client)).Methods(http.MethodPost)
+	r.HandleFunc("/login", handlers.AuthHandler(client, cfg.Auth.JWTSecret, cfg.Auth.TokenTTL)).Methods(http.MethodPost)
 
 	// Protected routes
 	protectedRouter := r.PathPrefix("/in").Subrouter()
 	protectedRouter.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(middleware.AuthMiddleware(next.ServeHTTP, client))
+		return http.HandlerFunc(middleware.AuthMiddleware(next.ServeHTTP, client, cfg.Auth.JWTSecret))
 	})
 
 	// User protected routes
-	protectedRouter.HandleFunc("/user", handlers.UpdateUserHandler(client)).Methods(http.MethodPut)
-	protectedRouter.HandleFunc("/user", handlers.DeleteUserHandler(client)).Methods(http.MethodDelete)
-	protectedRouter.HandleFunc("/{id}", handlers.GetUserDataHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/user", handlers.UpdateUserHandler(client, index, timelines)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/user", handlers.DeleteUserHandler(client, minioClient, cfg.Minio.Bucket, index)).Methods(http.MethodDelete)
 	protectedRouter.HandleFunc("/", handlers.FeedHandler(client)).Methods(http.MethodGet)
 
 	// Media protected routes
-	protectedRouter.HandleFunc("/media/upload", handlers.UploadMediaHandler(client, minioClient)).Methods(http.MethodPost)
-	protectedRouter.HandleFunc("/media/{id}", handlers.GetMediaHandler(client)).Methods(http.MethodGet)
-	protectedRouter.HandleFunc("/media/{id}", handlers.UpdateMediaHandler(client, minioClient)).Methods(http.MethodPut)
-	protectedRouter.HandleFunc("/media/{id}", handlers.DeleteMediaHandler(client, minioClient)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/media/upload", handlers.UploadMediaHandler(client, minioClient, cfg.Minio.Bucket, index, timelines)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/media/{id}", handlers.GetMediaHandler(client, minioClient, cfg.Minio.Bucket)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/media/{id}", handlers.UpdateMediaHandler(client, minioClient, cfg.Minio.Bucket, index)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/media/{id}", handlers.DeleteMediaHandler(client, minioClient, cfg.Minio.Bucket, index)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/media/{id}/playback", handlers.PlaybackHandler(client, tracker)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/media/{id}/comments", handlers.CreateCommentHandler(client)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/media/{id}/comments/{commentId}", handlers.DeleteCommentHandler(client)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/media/{id}/reaction", handlers.ReactHandler(client)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/media/{id}/reaction", handlers.DeleteReactionHandler(client)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/media/{id}/bookmark", handlers.BookmarkMediaHandler(client)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/media/{id}/bookmark", handlers.DeleteBookmarkHandler(client)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/media/{id}/captions", handlers.GetCaptionsHandler(client, minioClient, cfg.Minio.Bucket)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/media/{id}/captions/{language}", handlers.GetCaptionHandler(client, minioClient, cfg.Minio.Bucket)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/media/{id}/captions/{language}", handlers.UploadCaptionHandler(client, minioClient, cfg.Minio.Bucket)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/media/{id}/captions/{language}", handlers.DeleteCaptionHandler(client, minioClient, cfg.Minio.Bucket)).Methods(http.MethodDelete)
 	protectedRouter.HandleFunc("/medias/timeline", handlers.GetMediasTimelineHandler(client)).Methods(http.MethodGet)
 
-	return c.Handler(r)
+	// Playlist protected routes
+	protectedRouter.HandleFunc("/playlists", handlers.GetPlaylistsHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/playlists", handlers.CreatePlaylistHandler(client)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/playlists/watch-later", handlers.GetWatchLaterHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/playlists/{id}", handlers.GetPlaylistHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/playlists/{id}", handlers.UpdatePlaylistHandler(client)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/playlists/{id}", handlers.DeletePlaylistHandler(client)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/playlists/{id}/items", handlers.AddPlaylistItemHandler(client)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/playlists/{id}/items/order", handlers.ReorderPlaylistHandler(client)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/playlists/{id}/items/{mediaId}", handlers.RemovePlaylistItemHandler(client)).Methods(http.MethodDelete)
+
+	// Bookmark protected routes
+	protectedRouter.HandleFunc("/bookmarks", handlers.GetBookmarksHandler(client)).Methods(http.MethodGet)
+
+	// History protected routes
+	protectedRouter.HandleFunc("/history", handlers.GetHistoryHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/history", handlers.DeleteHistoryHandler(client)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/history/settings", handlers.UpdateHistorySettingsHandler(client)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/history/{mediaId}", handlers.GetHistoryEntryHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/history/{mediaId}", handlers.DeleteHistoryEntryHandler(client)).Methods(http.MethodDelete)
+
+	// Analytics protected routes
+	protectedRouter.HandleFunc("/analytics/overview", handlers.AnalyticsOverviewHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/analytics/media/{id}", handlers.MediaAnalyticsHandler(client)).Methods(http.MethodGet)
+
+	// Follow protected routes
+	protectedRouter.HandleFunc("/users/{id}/follow", handlers.FollowUserHandler(client, timelines)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/users/{id}/follow", handlers.UnfollowUserHandler(client, timelines)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/feed/following", handlers.FollowingFeedHandler(client, timelines)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/users/{id}/followers", handlers.GetFollowersHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/users/{id}/following", handlers.GetFollowingHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/follow-requests", handlers.GetFollowRequestsHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/follow-requests/{id}/approve", handlers.ApproveFollowRequestHandler(client, timelines)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/follow-requests/{id}", handlers.RejectFollowRequestHandler(client)).Methods(http.MethodDelete)
+
+	// Block and mute protected routes
+	protectedRouter.HandleFunc("/users/{id}/block", handlers.BlockUserHandler(client, timelines)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/users/{id}/block", handlers.UnblockUserHandler(client)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/users/{id}/mute", handlers.MuteUserHandler(client)).Methods(http.MethodPut)
+	protectedRouter.HandleFunc("/users/{id}/mute", handlers.UnmuteUserHandler(client)).Methods(http.MethodDelete)
+	protectedRouter.HandleFunc("/blocks", handlers.GetBlocksHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/mutes", handlers.GetMutesHandler(client)).Methods(http.MethodGet)
+
+	// Message protected routes
+	protectedRouter.HandleFunc("/conversations", handlers.GetConversationsHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/conversations", handlers.CreateConversationHandler(client)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/conversations/stream", handlers.StreamMessagesHandler(hub)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/conversations/{id}/messages", handlers.GetMessagesHandler(client)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/conversations/{id}/messages", handlers.SendMessageHandler(client, hub)).Methods(http.MethodPost)
+	protectedRouter.HandleFunc("/conversations/{id}/read", handlers.MarkConversationReadHandler(client, hub)).Methods(http.MethodPost)
+
+	// Explore protected routes
+	protectedRouter.HandleFunc("/explore", handlers.ExploreHandler(trends)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/explore/{subject}", handlers.ExploreSubjectHandler(client, trends)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/trending", handlers.TrendingHandler(client, trends)).Methods(http.MethodGet)
+	protectedRouter.HandleFunc("/hashtags/{tag}", handlers.GetHashtagHandler(client)).Methods(http.MethodGet)
+
+	// Search protected routes
+	protectedRouter.HandleFunc("/search", handlers.SearchHandler(client, index)).Methods(http.MethodGet)
+
+	// Report protected routes
+	protectedRouter.HandleFunc("/reports", handlers.CreateReportHandler(client)).Methods(http.MethodPost)
+
+	// Moderation routes, only for moderators and admins
+	adminRouter := protectedRouter.PathPrefix("/admin").Subrouter()
+	adminRouter.Use(middleware.RequireRole(models.RoleModerator, models.RoleAdmin))
+
+	adminRouter.HandleFunc("/reports", handlers.GetReportsHandler(client)).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/reports/{id}", handlers.GetReportHandler(client)).Methods(http.MethodGet)
+	adminRouter.HandleFunc("/reports/{id}/actions", handlers.ModerateReportHandler(client, minioClient, cfg.Minio.Bucket, index)).Methods(http.MethodPost)
+	adminRouter.Handle("/users/{id}/role", middleware.RequireRole(models.RoleAdmin)(handlers.UpdateUserRoleHandler(client))).Methods(http.MethodPut)
+
+	// The profile route matches any single segment, so it has to stay last.
+	protectedRouter.HandleFunc("/{id}", handlers.GetUserDataHandler(client)).Methods(http.MethodGet)
+
+	return r, nil
+}
+
+// NewHTTPServer wraps handler in an http.Server with the timeouts and TLS
+// settings of cfg. Long-lived responses such as event streams lift the
+// write timeout themselves.
+func NewHTTPServer(cfg *Config, handler http.Handler) *http.Server {
+	server := &http.Server{
+		Addr:              cfg.Server.Addr(),
+		Handler:           handler,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+	}
+
+	if cfg.Server.TLS.Enabled() {
+		server.TLSConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+		}
+		if !cfg.Server.TLS.HTTP2 {
+			// A non-nil, empty map keeps net/http from offering h2.
+			server.TLSNextProto = map[string]func(*http.Server, *tls.Conn, http.Handler){}
+		}
+	}
+
+	return server
+}
+
+// Serve runs server until it is shut down, over HTTPS when cfg enables it.
+// It returns nil once Shutdown or Close has been called.
+func Serve(cfg *Config, server *http.Server) error {
+	var err error
+	if cfg.Server.TLS.Enabled() {
+		err = server.ListenAndServeTLS(cfg.Server.TLS.CertFile, cfg.Server.TLS.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+
+	return err
 }
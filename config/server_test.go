@@ -0,0 +1,68 @@
+package config
+
+import (
+	"sort"
+	"testing"
+	"vilow-be/pkg/openapi"
+
+	"github.com/gorilla/mux"
+)
+
+// TestRoutesDocumented fails when a route is missing from the OpenAPI
+// document, or when the document describes a route that no longer exists.
+func TestRoutesDocumented(t *testing.T) {
+	router, err := NewRouter(Default(), nil, nil, nil, nil, nil, nil, nil, nil, nil)
+	if err != nil {
+		t.Fatalf("NewRouter() error = %v", err)
+	}
+
+	routes := map[string]bool{}
+	err = router.Walk(func(route *mux.Route, _ *mux.Router, _ []*mux.Route) error {
+		path, err := route.GetPathTemplate()
+		if err != nil {
+			return nil
+		}
+		// Subrouter prefixes have no methods of their own.
+		methods, err := route.GetMethods()
+		if err != nil {
+			return nil
+		}
+
+		for _, method := range methods {
+			routes[method+" "+path] = true
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walk routes: %v", err)
+	}
+
+	spec, err := openapi.New()
+	if err != nil {
+		t.Fatalf("load OpenAPI document: %v", err)
+	}
+
+	operations, err := spec.Operations()
+	if err != nil {
+		t.Fatalf("read OpenAPI operations: %v", err)
+	}
+
+	documented := map[string]bool{}
+	for _, operation := range operations {
+		documented[operation] = true
+		if !routes[operation] {
+			t.Errorf("%s is documented but not routed", operation)
+		}
+	}
+
+	undocumented := []string{}
+	for route := range routes {
+		if !documented[route] {
+			undocumented = append(undocumented, route)
+		}
+	}
+	sort.Strings(undocumented)
+	for _, route := range undocumented {
+		t.Errorf("%s has no entry in pkg/openapi/openapi.yaml", route)
+	}
+}
@@ -0,0 +1,19 @@
+package config
+
+import (
+	"context"
+	"vilow-be/pkg/tracing"
+)
+
+// SetupTracing is a function that sets up OpenTelemetry tracing. The
+// returned function flushes the spans still buffered.
+func SetupTracing(ctx context.Context, cfg *Config) (func(context.Context) error, error) {
+	return tracing.Setup(ctx, tracing.Options{
+		Exporter:    cfg.Tracing.Exporter,
+		ServiceName: cfg.Tracing.ServiceName,
+		SampleRatio: cfg.Tracing.SampleRatio,
+		File:        cfg.Tracing.File,
+		Endpoint:    cfg.Tracing.Endpoint,
+		Insecure:    cfg.Tracing.Insecure,
+	})
+}
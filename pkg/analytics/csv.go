@@ -0,0 +1,49 @@
+package analytics
+
+import (
+	"encoding/csv"
+	"io"
+	"strconv"
+	"time"
+)
+
+var csvHeader = []string{
+	"date",
+	"views",
+	"unique_viewers",
+	"watch_time_seconds",
+	"average_watch_percentage",
+	"likes",
+	"dislikes",
+	"comments",
+	"follower_gains",
+}
+
+// WriteCSV writes one row per day of the report.
+func WriteCSV(w io.Writer, r *Report) error {
+	writer := csv.NewWriter(w)
+
+	if err := writer.Write(csvHeader); err != nil {
+		return err
+	}
+
+	for _, d := range r.Days {
+		err := writer.Write([]string{
+			d.Date.Format(time.DateOnly),
+			strconv.Itoa(d.Views),
+			strconv.Itoa(d.UniqueViewers),
+			strconv.Itoa(d.WatchTime),
+			strconv.FormatFloat(d.AverageWatchPercentage, 'f', 2, 64),
+			strconv.Itoa(d.Likes),
+			strconv.Itoa(d.Dislikes),
+			strconv.Itoa(d.Comments),
+			strconv.Itoa(d.FollowerGains),
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	writer.Flush()
+	return writer.Error()
+}
@@ -0,0 +1,154 @@
+package analytics
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+const (
+	// DefaultRange is the period reported when no dates are given.
+	DefaultRange = 28 * 24 * time.Hour
+	// MaxDays bounds how many days a single report may span.
+	MaxDays = 366
+
+	day = 24 * time.Hour
+)
+
+var ErrInvalidRange = errors.New("from must not be after to and the range must not exceed 366 days")
+
+// Day holds the numbers of one UTC day. Totals use the same shape.
+type Day struct {
+	Date                   time.Time
+	Views                  int
+	UniqueViewers          int
+	WatchTime              int
+	AverageWatchPercentage float64
+	Likes                  int
+	Dislikes               int
+	Comments               int
+	FollowerGains          int
+
+	// expectedWatchTime is views times duration, the watch time that would
+	// have been reached had every view been watched to the end. measuredWatchTime
+	// is the part of WatchTime it compares to, media of unknown length left out.
+	expectedWatchTime float64
+	measuredWatchTime int
+}
+
+// Report accumulates daily numbers between From and To, both inclusive UTC
+// days. Call Finish once everything was added.
+type Report struct {
+	From   time.Time
+	To     time.Time
+	Totals Day
+	Days   []Day
+}
+
+func Truncate(t time.Time) time.Time {
+	return t.UTC().Truncate(day)
+}
+
+func NewReport(from, to time.Time) (*Report, error) {
+	from, to = Truncate(from), Truncate(to)
+	if from.After(to) || int(to.Sub(from)/day)+1 > MaxDays {
+		return nil, ErrInvalidRange
+	}
+
+	report := &Report{From: from, To: to}
+	for date := from; !date.After(to); date = date.Add(day) {
+		report.Days = append(report.Days, Day{Date: date})
+	}
+
+	return report, nil
+}
+
+// End returns the first instant after the reported range, for use as an
+// exclusive upper bound in queries.
+func (r *Report) End() time.Time {
+	return r.To.Add(day)
+}
+
+func (r *Report) at(t time.Time) *Day {
+	t = Truncate(t)
+	if t.Before(r.From) || t.After(r.To) {
+		return nil
+	}
+	return &r.Days[int(t.Sub(r.From)/day)]
+}
+
+// AddPlayback adds the views and watch time of a media on date. duration is
+// the media length in seconds, zero when unknown.
+func (r *Report) AddPlayback(date time.Time, views, watchTime int, duration float64) {
+	if d := r.at(date); d != nil {
+		d.Views += views
+		d.WatchTime += watchTime
+		if duration > 0 {
+			d.expectedWatchTime += float64(views) * duration
+			d.measuredWatchTime += watchTime
+		}
+	}
+}
+
+func (r *Report) AddViewer(date time.Time) {
+	if d := r.at(date); d != nil {
+		d.UniqueViewers++
+	}
+}
+
+func (r *Report) AddLike(date time.Time) {
+	if d := r.at(date); d != nil {
+		d.Likes++
+	}
+}
+
+func (r *Report) AddDislike(date time.Time) {
+	if d := r.at(date); d != nil {
+		d.Dislikes++
+	}
+}
+
+func (r *Report) AddComment(date time.Time) {
+	if d := r.at(date); d != nil {
+		d.Comments++
+	}
+}
+
+func (r *Report) AddFollower(date time.Time) {
+	if d := r.at(date); d != nil {
+		d.FollowerGains++
+	}
+}
+
+// Finish computes the watch percentages and the totals. Unique viewers in
+// the totals are the sum of the daily values, so a viewer returning on
+// another day is counted again.
+func (r *Report) Finish() {
+	r.Totals = Day{}
+
+	for i := range r.Days {
+		d := &r.Days[i]
+		d.AverageWatchPercentage = watchPercentage(d.measuredWatchTime, d.expectedWatchTime)
+
+		r.Totals.Views += d.Views
+		r.Totals.UniqueViewers += d.UniqueViewers
+		r.Totals.WatchTime += d.WatchTime
+		r.Totals.Likes += d.Likes
+		r.Totals.Dislikes += d.Dislikes
+		r.Totals.Comments += d.Comments
+		r.Totals.FollowerGains += d.FollowerGains
+		r.Totals.expectedWatchTime += d.expectedWatchTime
+		r.Totals.measuredWatchTime += d.measuredWatchTime
+	}
+
+	r.Totals.AverageWatchPercentage = watchPercentage(r.Totals.measuredWatchTime, r.Totals.expectedWatchTime)
+}
+
+func watchPercentage(watchTime int, expected float64) float64 {
+	if expected <= 0 {
+		return 0
+	}
+
+	percentage := math.Min(100, float64(watchTime)/expected*100)
+	return math.Round(percentage*100) / 100
+}
@@ -0,0 +1,112 @@
+package analytics
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func date(day int) time.Time {
+	return time.Date(2024, 3, day, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNewReport(t *testing.T) {
+	tests := []struct {
+		name     string
+		from, to time.Time
+		wantDays int
+		wantErr  error
+	}{
+		{name: "single day", from: date(1), to: date(1), wantDays: 1},
+		{name: "times are truncated to days", from: date(1).Add(23 * time.Hour), to: date(3).Add(time.Minute), wantDays: 3},
+		{name: "other time zones are read as UTC", from: time.Date(2024, 3, 1, 22, 0, 0, 0, time.FixedZone("", -3*3600)), to: date(2), wantDays: 1},
+		{name: "longest range", from: date(1), to: date(1).AddDate(0, 0, MaxDays-1), wantDays: MaxDays},
+		{name: "too long", from: date(1), to: date(1).AddDate(0, 0, MaxDays), wantErr: ErrInvalidRange},
+		{name: "from after to", from: date(2), to: date(1), wantErr: ErrInvalidRange},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report, err := NewReport(tt.from, tt.to)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("NewReport() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if len(report.Days) != tt.wantDays {
+				t.Errorf("len(Days) = %d, want %d", len(report.Days), tt.wantDays)
+			}
+			if !report.End().Equal(report.To.Add(24 * time.Hour)) {
+				t.Errorf("End() = %v, want the day after %v", report.End(), report.To)
+			}
+		})
+	}
+}
+
+func TestReportFinish(t *testing.T) {
+	report, err := NewReport(date(1), date(3))
+	if err != nil {
+		t.Fatalf("NewReport() error = %v", err)
+	}
+
+	// Two views of a 100 second media, 150 seconds watched.
+	report.AddPlayback(date(1).Add(10*time.Hour), 2, 150, 100)
+	// Length unknown, counted as watch time but left out of the percentages.
+	report.AddPlayback(date(2), 1, 40, 0)
+	// Watched more than its length by seeking back.
+	report.AddPlayback(date(3), 1, 90, 60)
+	// Outside the range.
+	report.AddPlayback(date(4), 5, 500, 100)
+
+	report.AddViewer(date(1))
+	report.AddViewer(date(1))
+	report.AddViewer(date(2))
+	report.AddLike(date(1))
+	report.AddDislike(date(2))
+	report.AddComment(date(3))
+	report.AddComment(date(3))
+	report.AddFollower(date(1).Add(-time.Second))
+	report.AddFollower(date(3))
+
+	report.Finish()
+
+	tests := []struct {
+		name string
+		got  Day
+		want Day
+	}{
+		{
+			name: "first day",
+			got:  report.Days[0],
+			want: Day{Views: 2, UniqueViewers: 2, WatchTime: 150, AverageWatchPercentage: 75, Likes: 1},
+		},
+		{
+			name: "unknown length",
+			got:  report.Days[1],
+			want: Day{Views: 1, UniqueViewers: 1, WatchTime: 40, Dislikes: 1},
+		},
+		{
+			name: "percentage is capped",
+			got:  report.Days[2],
+			want: Day{Views: 1, WatchTime: 90, AverageWatchPercentage: 100, Comments: 2, FollowerGains: 1},
+		},
+		{
+			name: "totals",
+			got:  report.Totals,
+			want: Day{Views: 4, UniqueViewers: 3, WatchTime: 280, AverageWatchPercentage: 92.31, Likes: 1, Dislikes: 1, Comments: 2, FollowerGains: 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.got
+			got.Date = time.Time{}
+			got.expectedWatchTime = 0
+			got.measuredWatchTime = 0
+			if got != tt.want {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package apierror
+
+import (
+	"encoding/json"
+	"net/http"
+	"vilow-be/pkg/logging"
+)
+
+// Codes are part of the API: clients branch on them, so they never change
+// once published. Messages are for humans and may be reworded.
+const (
+	CodeBadRequest         = "bad_request"
+	CodeValidationFailed   = "validation_failed"
+	CodeUnauthorized       = "unauthorized"
+	CodeInvalidCredentials = "invalid_credentials"
+	CodeInvalidToken       = "invalid_token"
+	CodeForbidden          = "forbidden"
+	CodeNotFound           = "not_found"
+	CodeMethodNotAllowed   = "method_not_allowed"
+	CodeConflict           = "conflict"
+	CodeGone               = "gone"
+	CodePayloadTooLarge    = "payload_too_large"
+	CodeUnsupportedMedia   = "unsupported_media_type"
+	CodeTooManyRequests    = "too_many_requests"
+	CodeInternal           = "internal_error"
+	CodeUnavailable        = "service_unavailable"
+)
+
+var codesByStatus = map[int]string{
+	http.StatusBadRequest:            CodeBadRequest,
+	http.StatusUnauthorized:          CodeUnauthorized,
+	http.StatusForbidden:             CodeForbidden,
+	http.StatusNotFound:              CodeNotFound,
+	http.StatusMethodNotAllowed:      CodeMethodNotAllowed,
+	http.StatusConflict:              CodeConflict,
+	http.StatusGone:                  CodeGone,
+	http.StatusRequestEntityTooLarge: CodePayloadTooLarge,
+	http.StatusUnsupportedMediaType:  CodeUnsupportedMedia,
+	http.StatusUnprocessableEntity:   CodeValidationFailed,
+	http.StatusTooManyRequests:       CodeTooManyRequests,
+	http.StatusInternalServerError:   CodeInternal,
+	http.StatusServiceUnavailable:    CodeUnavailable,
+}
+
+// FieldError points at one invalid field of a request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// APIError is the body of every error response, wrapped in an "error" key.
+type APIError struct {
+	Status    int          `json:"status"`
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Fields    []FieldError `json:"fields,omitempty"`
+	RequestID string       `json:"requestId,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	return e.Message
+}
+
+type envelope struct {
+	Error *APIError `json:"error"`
+}
+
+// New returns an error with an explicit code, for the cases where the one
+// implied by the status is too vague.
+func New(status int, code, message string) *APIError {
+	return &APIError{Status: status, Code: code, Message: message}
+}
+
+// Validation returns a 400 listing every invalid field.
+func Validation(fields ...FieldError) *APIError {
+	return &APIError{
+		Status:  http.StatusBadRequest,
+		Code:    CodeValidationFailed,
+		Message: "The request has invalid fields",
+		Fields:  fields,
+	}
+}
+
+// CodeForStatus returns the code used for status when none is given.
+func CodeForStatus(status int) string {
+	if code, ok := codesByStatus[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return CodeInternal
+	}
+
+	return CodeBadRequest
+}
+
+// Error replies with message and status in the error envelope, the code
+// being the one of the status. It is the drop-in for http.Error.
+func Error(w http.ResponseWriter, r *http.Request, message string, status int) {
+	Write(w, r, &APIError{Status: status, Code: CodeForStatus(status), Message: message})
+}
+
+// Write replies with err in the error envelope, tagged with the request ID.
+// Server errors are logged, their message is meant to be generic.
+func Write(w http.ResponseWriter, r *http.Request, err *APIError) {
+	response := *err
+	response.RequestID = logging.RequestID(r.Context())
+	if response.Code == "" {
+		response.Code = CodeForStatus(response.Status)
+	}
+
+	if response.Status >= http.StatusInternalServerError {
+		logging.FromContext(r.Context()).Error(response.Message, "code", response.Code, "status", response.Status)
+	}
+
+	// Errors must not be cached or sniffed into something else.
+	w.Header().Del("Content-Length")
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(response.Status)
+
+	_ = json.NewEncoder(w).Encode(envelope{Error: &response})
+}
@@ -0,0 +1,149 @@
+package apierror
+
+import (
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"vilow-be/pkg/logging"
+)
+
+func TestCodeForStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   string
+	}{
+		{status: http.StatusBadRequest, want: CodeBadRequest},
+		{status: http.StatusUnauthorized, want: CodeUnauthorized},
+		{status: http.StatusForbidden, want: CodeForbidden},
+		{status: http.StatusNotFound, want: CodeNotFound},
+		{status: http.StatusConflict, want: CodeConflict},
+		{status: http.StatusRequestEntityTooLarge, want: CodePayloadTooLarge},
+		{status: http.StatusUnprocessableEntity, want: CodeValidationFailed},
+		{status: http.StatusTooManyRequests, want: CodeTooManyRequests},
+		{status: http.StatusInternalServerError, want: CodeInternal},
+		{status: http.StatusServiceUnavailable, want: CodeUnavailable},
+		{status: http.StatusTeapot, want: CodeBadRequest},
+		{status: http.StatusBadGateway, want: CodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			if got := CodeForStatus(tt.status); got != tt.want {
+				t.Errorf("CodeForStatus(%d) = %q, want %q", tt.status, got, tt.want)
+			}
+		})
+	}
+}
+
+// serve runs write behind the logging middleware, so the request carries an
+// ID, and returns the response.
+func serve(t *testing.T, write http.HandlerFunc) *http.Response {
+	t.Helper()
+
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	handler := logging.Middleware(logger)(write)
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(logging.RequestIDHeader, "req-1")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, req)
+
+	return recorder.Result()
+}
+
+func TestWrite(t *testing.T) {
+	tests := []struct {
+		name       string
+		write      http.HandlerFunc
+		wantStatus int
+		wantBody   string
+	}{
+		{
+			name: "status code",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				Error(w, r, "Media not found", http.StatusNotFound)
+			},
+			wantStatus: http.StatusNotFound,
+			wantBody:   `{"error":{"status":404,"code":"not_found","message":"Media not found","requestId":"req-1"}}`,
+		},
+		{
+			name: "explicit code",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				Write(w, r, New(http.StatusUnauthorized, CodeInvalidCredentials, "Invalid email or password"))
+			},
+			wantStatus: http.StatusUnauthorized,
+			wantBody:   `{"error":{"status":401,"code":"invalid_credentials","message":"Invalid email or password","requestId":"req-1"}}`,
+		},
+		{
+			name: "missing code",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				Write(w, r, &APIError{Status: http.StatusInternalServerError, Message: "Internal server error"})
+			},
+			wantStatus: http.StatusInternalServerError,
+			wantBody:   `{"error":{"status":500,"code":"internal_error","message":"Internal server error","requestId":"req-1"}}`,
+		},
+		{
+			name: "validation fields",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				Write(w, r, Validation(FieldError{Field: "email", Message: "must be an email address"}))
+			},
+			wantStatus: http.StatusBadRequest,
+			wantBody:   `{"error":{"status":400,"code":"validation_failed","message":"The request has invalid fields","fields":[{"field":"email","message":"must be an email address"}],"requestId":"req-1"}}`,
+		},
+		{
+			name: "replaces a pending content length",
+			write: func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Length", "1024")
+				Error(w, r, "Too many requests", http.StatusTooManyRequests)
+			},
+			wantStatus: http.StatusTooManyRequests,
+			wantBody:   `{"error":{"status":429,"code":"too_many_requests","message":"Too many requests","requestId":"req-1"}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := serve(t, tt.write)
+			defer resp.Body.Close()
+
+			if resp.StatusCode != tt.wantStatus {
+				t.Errorf("status = %d, want %d", resp.StatusCode, tt.wantStatus)
+			}
+
+			wantHeaders := map[string]string{
+				"Content-Type":           "application/json; charset=utf-8",
+				"X-Content-Type-Options": "nosniff",
+				"Cache-Control":          "no-store",
+				"Content-Length":         "",
+			}
+			for name, want := range wantHeaders {
+				if got := resp.Header.Get(name); got != want {
+					t.Errorf("header %s = %q, want %q", name, got, want)
+				}
+			}
+
+			var got, want interface{}
+			if err := json.NewDecoder(resp.Body).Decode(&got); err != nil {
+				t.Fatalf("decoding body: %v", err)
+			}
+			json.Unmarshal([]byte(tt.wantBody), &want)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("body = %v, want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestWriteWithoutRequestID(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	Error(recorder, httptest.NewRequest(http.MethodGet, "/", nil), "Forbidden", http.StatusForbidden)
+
+	want := `{"error":{"status":403,"code":"forbidden","message":"Forbidden"}}` + "\n"
+	if got := recorder.Body.String(); got != want {
+		t.Errorf("body = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,242 @@
+package captions
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	ContentType = "text/vtt; charset=utf-8"
+
+	// MaxSize is the largest caption file accepted, in bytes.
+	MaxSize = 1 << 20
+)
+
+var (
+	ErrEmpty           = errors.New("caption file is empty")
+	ErrTooLarge        = fmt.Errorf("caption file is larger than %d bytes", MaxSize)
+	ErrInvalidEncoding = errors.New("caption file must be UTF-8 encoded")
+	ErrInvalidLanguage = errors.New("language must be a tag like en or pt-BR")
+
+	languagePattern = regexp.MustCompile(`^[a-zA-Z]{2,3}(-[a-zA-Z0-9]{2,8})*$`)
+	// Hours are optional in WebVTT, SRT always has them and uses a comma.
+	timingPattern = regexp.MustCompile(`^((?:\d+:)?\d{2}:\d{2}[.,]\d{3})\s+-->\s+((?:\d+:)?\d{2}:\d{2}[.,]\d{3})(.*)$`)
+)
+
+// Cue is a single caption with the time range it is shown in.
+type Cue struct {
+	ID       string
+	Start    time.Duration
+	End      time.Duration
+	Settings string
+	Text     string
+}
+
+// NormalizeLanguage validates a language tag and returns it in its usual
+// casing: lower case language, upper case region.
+func NormalizeLanguage(language string) (string, error) {
+	language = strings.TrimSpace(language)
+	if !languagePattern.MatchString(language) {
+		return "", ErrInvalidLanguage
+	}
+
+	parts := strings.Split(language, "-")
+	parts[0] = strings.ToLower(parts[0])
+	for i := 1; i < len(parts); i++ {
+		if len(parts[i]) == 2 {
+			parts[i] = strings.ToUpper(parts[i])
+		}
+	}
+
+	return strings.Join(parts, "-"), nil
+}
+
+// Parse reads an SRT or WebVTT file. WebVTT is recognised by its header,
+// everything else is read as SRT.
+func Parse(data []byte) ([]Cue, error) {
+	if len(data) == 0 {
+		return nil, ErrEmpty
+	}
+	if len(data) > MaxSize {
+		return nil, ErrTooLarge
+	}
+	if !utf8.Valid(data) {
+		return nil, ErrInvalidEncoding
+	}
+
+	text := string(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\r", "\n")
+
+	blocks := splitBlocks(text)
+	if len(blocks) == 0 {
+		return nil, ErrEmpty
+	}
+
+	webVTT := strings.HasPrefix(blocks[0], "WEBVTT")
+	if webVTT {
+		blocks = blocks[1:]
+	}
+
+	cues := []Cue{}
+	for _, block := range blocks {
+		// WebVTT files may carry comments and style or region definitions
+		// that players handle themselves and that have no timing line.
+		if webVTT && (strings.HasPrefix(block, "NOTE") || strings.HasPrefix(block, "STYLE") || strings.HasPrefix(block, "REGION")) {
+			continue
+		}
+
+		cue, err := parseCue(block)
+		if err != nil {
+			return nil, err
+		}
+		cues = append(cues, cue)
+	}
+
+	if len(cues) == 0 {
+		return nil, ErrEmpty
+	}
+
+	return cues, nil
+}
+
+// ToWebVTT parses an SRT or WebVTT file and writes it back as WebVTT.
+func ToWebVTT(data []byte) ([]byte, error) {
+	cues, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+
+	return Format(cues), nil
+}
+
+// Format writes cues as a WebVTT file.
+func Format(cues []Cue) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("WEBVTT\n")
+
+	for _, cue := range cues {
+		buf.WriteString("\n")
+		if cue.ID != "" {
+			buf.WriteString(cue.ID + "\n")
+		}
+		buf.WriteString(formatTimestamp(cue.Start) + " --> " + formatTimestamp(cue.End))
+		if cue.Settings != "" {
+			buf.WriteString(" " + cue.Settings)
+		}
+		buf.WriteString("\n" + cue.Text + "\n")
+	}
+
+	return buf.Bytes()
+}
+
+// splitBlocks splits text on blank lines. Lines holding only whitespace
+// count as blank too, editors often leave spaces behind.
+func splitBlocks(text string) []string {
+	blocks := []string{}
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, line)
+			continue
+		}
+		if len(lines) > 0 {
+			blocks = append(blocks, strings.Join(lines, "\n"))
+			lines = lines[:0]
+		}
+	}
+	if len(lines) > 0 {
+		blocks = append(blocks, strings.Join(lines, "\n"))
+	}
+
+	return blocks
+}
+
+func parseCue(block string) (Cue, error) {
+	lines := strings.Split(block, "\n")
+	cue := Cue{}
+
+	if !strings.Contains(lines[0], "-->") {
+		cue.ID = strings.TrimSpace(lines[0])
+		lines = lines[1:]
+		if len(lines) == 0 {
+			return Cue{}, fmt.Errorf("cue %q has no timing line", cue.ID)
+		}
+	}
+
+	match := timingPattern.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if match == nil {
+		return Cue{}, fmt.Errorf("invalid timing line %q", lines[0])
+	}
+
+	start, err := parseTimestamp(match[1])
+	if err != nil {
+		return Cue{}, err
+	}
+	end, err := parseTimestamp(match[2])
+	if err != nil {
+		return Cue{}, err
+	}
+	if end <= start {
+		return Cue{}, fmt.Errorf("cue ending at %s does not end after it starts", match[2])
+	}
+
+	// SRT numbers its cues, those numbers carry no meaning in WebVTT.
+	if _, err := strconv.Atoi(cue.ID); err == nil {
+		cue.ID = ""
+	}
+
+	cue.Start = start
+	cue.End = end
+	cue.Settings = strings.TrimSpace(match[3])
+	cue.Text = strings.Join(lines[1:], "\n")
+
+	if strings.TrimSpace(cue.Text) == "" {
+		return Cue{}, fmt.Errorf("cue at %s has no text", match[1])
+	}
+
+	return cue, nil
+}
+
+func parseTimestamp(value string) (time.Duration, error) {
+	value = strings.Replace(value, ",", ".", 1)
+
+	parts := strings.Split(value, ":")
+	if len(parts) == 2 {
+		parts = append([]string{"0"}, parts...)
+	}
+
+	hours, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+	minutes, err := strconv.Atoi(parts[1])
+	if err != nil || minutes > 59 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+	seconds, err := strconv.ParseFloat(parts[2], 64)
+	if err != nil || seconds >= 60 {
+		return 0, fmt.Errorf("invalid timestamp %q", value)
+	}
+
+	return time.Duration(hours)*time.Hour +
+		time.Duration(minutes)*time.Minute +
+		time.Duration(seconds*float64(time.Second)).Round(time.Millisecond), nil
+}
+
+func formatTimestamp(d time.Duration) string {
+	hours := d / time.Hour
+	d -= hours * time.Hour
+	minutes := d / time.Minute
+	d -= minutes * time.Minute
+	seconds := d / time.Second
+	d -= seconds * time.Second
+
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", hours, minutes, seconds, d/time.Millisecond)
+}
@@ -0,0 +1,138 @@
+package captions
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestToWebVTT(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		want  string
+	}{
+		{
+			name:  "srt",
+			input: "1\n00:00:01,000 --> 00:00:02,500\nHello\n\n2\n00:00:03,000 --> 00:00:04,000\nTwo\nlines\n",
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.500\nHello\n\n00:00:03.000 --> 00:00:04.000\nTwo\nlines\n",
+		},
+		{
+			name:  "srt with crlf and bom",
+			input: "\xef\xbb\xbf1\r\n00:00:01,000 --> 00:00:02,000\r\nHello\r\n\r\n2\r\n00:00:03,000 --> 00:00:04,000\r\nBye\r\n",
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n\n00:00:03.000 --> 00:00:04.000\nBye\n",
+		},
+		{
+			name:  "blank lines holding whitespace",
+			input: "1\n00:00:01,000 --> 00:00:02,000\nHello\n  \t\n2\n00:00:03,000 --> 00:00:04,000\nBye\n \n",
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n\n00:00:03.000 --> 00:00:04.000\nBye\n",
+		},
+		{
+			name:  "several blank lines between cues",
+			input: "1\n00:00:01,000 --> 00:00:02,000\nHello\n\n\n\n2\n00:00:03,000 --> 00:00:04,000\nBye",
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n\n00:00:03.000 --> 00:00:04.000\nBye\n",
+		},
+		{
+			name:  "srt without cue numbers",
+			input: "00:00:01,000 --> 00:00:02,000\nHello\n",
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n",
+		},
+		{
+			name:  "webvtt keeps ids and settings",
+			input: "WEBVTT\n\nintro\n00:01.000 --> 00:02.000 align:start\nHello\n",
+			want:  "WEBVTT\n\nintro\n00:00:01.000 --> 00:00:02.000 align:start\nHello\n",
+		},
+		{
+			name:  "webvtt skips notes and styles",
+			input: "WEBVTT - title\n\nNOTE made by hand\n\nSTYLE\n::cue { color: red }\n\n00:00:01.000 --> 00:00:02.000\nHello\n",
+			want:  "WEBVTT\n\n00:00:01.000 --> 00:00:02.000\nHello\n",
+		},
+		{
+			name:  "hours past 99",
+			input: "1\n100:00:00,000 --> 100:00:01,000\nLate\n",
+			want:  "WEBVTT\n\n100:00:00.000 --> 100:00:01.000\nLate\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ToWebVTT([]byte(tt.input))
+			if err != nil {
+				t.Fatalf("ToWebVTT() error = %v", err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("ToWebVTT() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestToWebVTTErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   []byte
+		wantErr error
+		message string
+	}{
+		{name: "empty", input: []byte{}, wantErr: ErrEmpty},
+		{name: "only blank lines", input: []byte("\n \n\t\n"), wantErr: ErrEmpty},
+		{name: "header only", input: []byte("WEBVTT\n"), wantErr: ErrEmpty},
+		{name: "too large", input: make([]byte, MaxSize+1), wantErr: ErrTooLarge},
+		{name: "not utf-8", input: []byte("1\n00:00:01,000 --> 00:00:02,000\n\xff\n"), wantErr: ErrInvalidEncoding},
+		{name: "bad timing", input: []byte("1\n00:00:01 --> 00:00:02\nHello\n"), message: "invalid timing line"},
+		{name: "ends before start", input: []byte("1\n00:00:02,000 --> 00:00:01,000\nHello\n"), message: "does not end after it starts"},
+		{name: "no text", input: []byte("1\n00:00:01,000 --> 00:00:02,000\n"), message: "has no text"},
+		{name: "minutes out of range", input: []byte("1\n00:61:00,000 --> 00:62:00,000\nHello\n"), message: "invalid timestamp"},
+		{name: "id without timing", input: []byte("WEBVTT\n\nintro\n"), message: "has no timing line"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ToWebVTT(tt.input)
+			if err == nil {
+				t.Fatal("ToWebVTT() error = nil")
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Errorf("ToWebVTT() error = %v, want %v", err, tt.wantErr)
+			}
+			if tt.message != "" && !strings.Contains(err.Error(), tt.message) {
+				t.Errorf("ToWebVTT() error = %v, want it to mention %q", err, tt.message)
+			}
+		})
+	}
+}
+
+func TestNormalizeLanguage(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "en", want: "en"},
+		{input: "PT-br", want: "pt-BR"},
+		{input: " es-419 ", want: "es-419"},
+		{input: "zh-hant-tw", want: "zh-hant-TW"},
+		{input: "e", wantErr: true},
+		{input: "english", wantErr: true},
+		{input: "en_US", wantErr: true},
+		{input: "", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := NormalizeLanguage(tt.input)
+			if tt.wantErr {
+				if !errors.Is(err, ErrInvalidLanguage) {
+					t.Errorf("NormalizeLanguage(%q) error = %v, want %v", tt.input, err, ErrInvalidLanguage)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("NormalizeLanguage(%q) error = %v", tt.input, err)
+			}
+			if got != tt.want {
+				t.Errorf("NormalizeLanguage(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,152 @@
+package chapters
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	// MinCount is how many timestamped lines a description needs before
+	// they are read as chapters rather than as a passing timestamp.
+	MinCount = 2
+
+	MaxTitleLength = 100
+)
+
+var (
+	ErrFirstNotAtStart = errors.New("the first chapter must start at 0:00")
+	ErrNotOrdered      = errors.New("chapters must be in increasing order of start time")
+	ErrEmptyTitle      = errors.New("every chapter needs a title")
+	ErrTitleTooLong    = fmt.Errorf("chapter titles can be at most %d characters", MaxTitleLength)
+
+	// TimestampPattern matches m:ss, mm:ss and h:mm:ss.
+	TimestampPattern = regexp.MustCompile(`^(?:(\d+):)?(\d{1,2}):([0-5]\d)$`)
+
+	linePattern = regexp.MustCompile(`^\s*[(\[]?((?:\d+:)?\d{1,2}:[0-5]\d)[)\]]?\s*(?:[-–—:|]\s*)?(.+?)\s*$`)
+)
+
+// Chapter is a named section of a video. Start is in seconds.
+type Chapter struct {
+	Start float64 `json:"start"`
+	Title string  `json:"title"`
+}
+
+// ParseTimestamp converts a timestamp like 1:02:03 to seconds.
+func ParseTimestamp(value string) (float64, bool) {
+	match := TimestampPattern.FindStringSubmatch(value)
+	if match == nil {
+		return 0, false
+	}
+
+	hours := 0
+	if match[1] != "" {
+		hours, _ = strconv.Atoi(match[1])
+	}
+	minutes, _ := strconv.Atoi(match[2])
+	seconds, _ := strconv.Atoi(match[3])
+
+	// Once hours are given, minutes cannot overflow into them.
+	if match[1] != "" && minutes > 59 {
+		return 0, false
+	}
+
+	return float64(hours*3600 + minutes*60 + seconds), true
+}
+
+// FormatTimestamp writes seconds the way ParseTimestamp reads them.
+func FormatTimestamp(seconds float64) string {
+	total := int(seconds)
+	if total >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", total/3600, total/60%60, total%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", total/60, total%60)
+}
+
+// Parse reads the chapter markers of a description: lines that start with
+// a timestamp followed by a title. Descriptions with fewer than MinCount
+// markers, or whose first marker is not at 0:00, have no chapters.
+func Parse(description string) []Chapter {
+	chapters := []Chapter{}
+
+	for _, line := range strings.Split(description, "\n") {
+		match := linePattern.FindStringSubmatch(line)
+		if match == nil {
+			continue
+		}
+
+		start, ok := ParseTimestamp(match[1])
+		if !ok {
+			continue
+		}
+
+		chapters = append(chapters, Chapter{Start: start, Title: match[2]})
+	}
+
+	if len(chapters) < MinCount || chapters[0].Start != 0 {
+		return nil
+	}
+
+	return chapters
+}
+
+// Validate checks that chapters start at 0:00, are strictly ordered, have
+// titles and start before the end of the video. A duration of zero means
+// it is not known yet and only the order is checked.
+func Validate(chapters []Chapter, duration float64) error {
+	if len(chapters) == 0 {
+		return nil
+	}
+
+	if chapters[0].Start != 0 {
+		return ErrFirstNotAtStart
+	}
+
+	for i, chapter := range chapters {
+		title := strings.TrimSpace(chapter.Title)
+		if title == "" {
+			return ErrEmptyTitle
+		}
+		if len([]rune(title)) > MaxTitleLength {
+			return ErrTitleTooLong
+		}
+		if i > 0 && chapter.Start <= chapters[i-1].Start {
+			return ErrNotOrdered
+		}
+		if duration > 0 && chapter.Start >= duration {
+			return fmt.Errorf("chapter %q starts at %s, after the end of the video", title, FormatTimestamp(chapter.Start))
+		}
+	}
+
+	return nil
+}
+
+// Trim drops the chapters starting at or after the end of the video, for
+// chapters saved before its duration was known.
+func Trim(chapters []Chapter, duration float64) []Chapter {
+	if duration <= 0 {
+		return chapters
+	}
+
+	trimmed := []Chapter{}
+	for _, chapter := range chapters {
+		if chapter.Start < duration {
+			trimmed = append(trimmed, chapter)
+		}
+	}
+
+	return trimmed
+}
+
+// Normalize trims the titles of chapters.
+func Normalize(chapters []Chapter) []Chapter {
+	normalized := make([]Chapter, len(chapters))
+	for i, chapter := range chapters {
+		normalized[i] = Chapter{Start: chapter.Start, Title: strings.TrimSpace(chapter.Title)}
+	}
+
+	return normalized
+}
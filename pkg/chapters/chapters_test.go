@@ -0,0 +1,148 @@
+package chapters
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   float64
+		wantOK bool
+	}{
+		{value: "0:00", want: 0, wantOK: true},
+		{value: "4:05", want: 245, wantOK: true},
+		{value: "75:00", want: 4500, wantOK: true},
+		{value: "1:02:03", want: 3723, wantOK: true},
+		{value: "1:60:00", wantOK: false},
+		{value: "1:60", wantOK: false},
+		{value: "123:4", wantOK: false},
+		{value: "abc", wantOK: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := ParseTimestamp(tt.value)
+			if ok != tt.wantOK || got != tt.want {
+				t.Errorf("ParseTimestamp(%q) = %v, %v, want %v, %v", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	tests := map[float64]string{
+		0:      "0:00",
+		245:    "4:05",
+		3599.9: "59:59",
+		3723:   "1:02:03",
+	}
+
+	for seconds, want := range tests {
+		if got := FormatTimestamp(seconds); got != want {
+			t.Errorf("FormatTimestamp(%v) = %q, want %q", seconds, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name        string
+		description string
+		want        []Chapter
+	}{
+		{
+			name:        "separators and brackets",
+			description: "Intro text\n0:00 Intro\n[1:30] - Setup\n(12:00): Wrap up \nthanks",
+			want:        []Chapter{{Start: 0, Title: "Intro"}, {Start: 90, Title: "Setup"}, {Start: 720, Title: "Wrap up"}},
+		},
+		{
+			name:        "hours",
+			description: "0:00 Start\n1:00:00 Second hour",
+			want:        []Chapter{{Start: 0, Title: "Start"}, {Start: 3600, Title: "Second hour"}},
+		},
+		{
+			name:        "a single timestamp is not a chapter list",
+			description: "0:00 Start\nsee 2:00 for the good part",
+		},
+		{
+			name:        "must start at zero",
+			description: "0:10 Late start\n1:00 Next",
+		},
+		{
+			name:        "no markers",
+			description: "just words",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.description); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		chapters []Chapter
+		duration float64
+		wantErr  error
+		message  string
+	}{
+		{name: "none", chapters: nil},
+		{name: "valid", chapters: []Chapter{{0, "Intro"}, {60, "Main"}}, duration: 120},
+		{name: "unknown duration", chapters: []Chapter{{0, "Intro"}, {6000, "Main"}}},
+		{name: "first not at start", chapters: []Chapter{{5, "Intro"}}, wantErr: ErrFirstNotAtStart},
+		{name: "not ordered", chapters: []Chapter{{0, "Intro"}, {60, "B"}, {60, "C"}}, wantErr: ErrNotOrdered},
+		{name: "blank title", chapters: []Chapter{{0, "  "}}, wantErr: ErrEmptyTitle},
+		{name: "long title", chapters: []Chapter{{0, strings.Repeat("é", MaxTitleLength+1)}}, wantErr: ErrTitleTooLong},
+		{name: "past the end", chapters: []Chapter{{0, "Intro"}, {120, "Outro"}}, duration: 120, message: `"Outro" starts at 2:00`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := Validate(tt.chapters, tt.duration)
+			switch {
+			case tt.wantErr != nil:
+				if !errors.Is(err, tt.wantErr) {
+					t.Errorf("Validate() error = %v, want %v", err, tt.wantErr)
+				}
+			case tt.message != "":
+				if err == nil || !strings.Contains(err.Error(), tt.message) {
+					t.Errorf("Validate() error = %v, want it to mention %q", err, tt.message)
+				}
+			case err != nil:
+				t.Errorf("Validate() error = %v", err)
+			}
+		})
+	}
+}
+
+func TestTrim(t *testing.T) {
+	list := []Chapter{{0, "Intro"}, {60, "Main"}, {120, "Outro"}}
+
+	tests := []struct {
+		name     string
+		duration float64
+		want     []Chapter
+	}{
+		{name: "unknown duration", duration: 0, want: list},
+		{name: "all fit", duration: 121, want: list},
+		{name: "starting at the end", duration: 120, want: list[:2]},
+		{name: "shorter", duration: 30, want: list[:1]},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Trim(list, tt.duration); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Trim(%v) = %+v, want %+v", tt.duration, got, tt.want)
+			}
+		})
+	}
+}
@@ -2,7 +2,8 @@ package dto
 
 import (
 	"time"
-	"vilow-be/prisma/db"
+	"vilow-be/pkg/chapters"
+	"vilow-be/pkg/entities"
 )
 
 type LoginResponse struct {
@@ -14,7 +15,7 @@ type LoginResponse struct {
 type User struct {
 	ID            string         `json:"id"`
 	Name          string         `json:"name"`
-	Email         string         `json:"email"`
+	Email         string         `json:"email,omitempty"`
 	StrID         string         `json:"strId"`
 	Description   string         `json:"description"`
 	Medias        []Media        `json:"medias"`
@@ -25,18 +26,30 @@ type User struct {
 	Dislikes      []Dislike      `json:"dislikes"`
 	Comments      []Comment      `json:"comments"`
 	Subjects      []string       `json:"subjects"`
+	Playlists     []Playlist     `json:"playlists"`
+	IsPrivate     bool           `json:"isPrivate"`
 }
 
 type Media struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Path        string    `json:"path"`
-	Description string    `json:"description"`
-	Subjects    []string  `json:"subjects"`
-	UserID      string    `json:"userId"`
-	Likes       []Like    `json:"likes"`
-	Dislikes    []Dislike `json:"dislikes"`
-	Comments    []Comment `json:"comments"`
+	ID           string             `json:"id"`
+	Name         string             `json:"name"`
+	Path         string             `json:"path"`
+	Description  string             `json:"description"`
+	Subjects     []string           `json:"subjects"`
+	Hashtags     []string           `json:"hashtags"`
+	Entities     []entities.Entity  `json:"entities"`
+	Chapters     []chapters.Chapter `json:"chapters"`
+	UserID       string             `json:"userId"`
+	ViewCount    int                `json:"viewCount"`
+	WatchTime    int                `json:"watchTime"`
+	Duration     float64            `json:"duration"`
+	Hidden       bool               `json:"hidden"`
+	CreatedAt    time.Time          `json:"createdAt"`
+	Likes        []Like             `json:"likes"`
+	Dislikes     []Dislike          `json:"dislikes"`
+	Comments     []Comment          `json:"comments"`
+	Captions     []Caption          `json:"captions,omitempty"`
+	IsBookmarked bool               `json:"isBookmarked"`
 }
 
 type Follow struct {
@@ -53,16 +66,19 @@ type Notification struct {
 }
 
 type AuthContext struct {
-	UserID   string   `json:"userId"`
-	Name     string   `json:"name"`
-	Email    string   `json:"email"`
-	StrID    string   `json:"strId"`
-	Subjects []string `json:"subjects"`
+	UserID        string   `json:"userId"`
+	Name          string   `json:"name"`
+	Email         string   `json:"email"`
+	StrID         string   `json:"strId"`
+	Subjects      []string `json:"subjects"`
+	HistoryPaused bool     `json:"historyPaused"`
+	Role          string   `json:"role"`
 }
 
 type FeedResponse struct {
-	UserAuthData AuthContext     `json:"userAuthData"`
-	Medias       []db.MediaModel `json:"medias"`
+	UserAuthData AuthContext `json:"userAuthData"`
+	Medias       []Media     `json:"medias"`
+	NextCursor   string      `json:"nextCursor"`
 }
 
 type Like struct {
@@ -78,8 +94,216 @@ type Dislike struct {
 }
 
 type Comment struct {
-	ID      string `json:"id"`
-	User    User   `json:"user"`
-	Media   Media  `json:"media"`
-	Content string `json:"content"`
+	ID        string            `json:"id"`
+	User      User              `json:"user"`
+	Media     Media             `json:"media"`
+	Content   string            `json:"content"`
+	Entities  []entities.Entity `json:"entities"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+type Playlist struct {
+	ID          string         `json:"id"`
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Visibility  string         `json:"visibility"`
+	Kind        string         `json:"kind"`
+	UserID      string         `json:"userId"`
+	Items       []PlaylistItem `json:"items"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	UpdatedAt   time.Time      `json:"updatedAt"`
+}
+
+type PlaylistItem struct {
+	ID       string    `json:"id"`
+	Position int       `json:"position"`
+	AddedAt  time.Time `json:"addedAt"`
+	Media    Media     `json:"media"`
+}
+
+type SearchResult struct {
+	Type  string  `json:"type"`
+	Score float64 `json:"score"`
+	Media *Media  `json:"media,omitempty"`
+	User  *User   `json:"user,omitempty"`
+}
+
+type SearchResponse struct {
+	Results    []SearchResult `json:"results"`
+	NextCursor string         `json:"nextCursor"`
+}
+
+type HashtagResponse struct {
+	Tag        string  `json:"tag"`
+	UsageCount int     `json:"usageCount"`
+	Medias     []Media `json:"medias"`
+	NextCursor string  `json:"nextCursor"`
+}
+
+type TimelineResponse struct {
+	Medias     []Media `json:"medias"`
+	NextCursor string  `json:"nextCursor"`
+}
+
+type SubjectCount struct {
+	Name       string `json:"name"`
+	MediaCount int    `json:"mediaCount"`
+	UserCount  int    `json:"userCount"`
+}
+
+type ExploreResponse struct {
+	Subjects    []SubjectCount `json:"subjects"`
+	GeneratedAt time.Time      `json:"generatedAt"`
+}
+
+type RankedMedia struct {
+	Media
+	Score float64 `json:"score"`
+}
+
+type RankedMediaResponse struct {
+	Subject     string        `json:"subject,omitempty"`
+	Medias      []RankedMedia `json:"medias"`
+	GeneratedAt time.Time     `json:"generatedAt"`
+}
+
+type HistoryEntry struct {
+	ID        string    `json:"id"`
+	Position  float64   `json:"position"`
+	Completed bool      `json:"completed"`
+	WatchedAt time.Time `json:"watchedAt"`
+	Media     Media     `json:"media"`
+}
+
+type HistoryResponse struct {
+	Paused     bool           `json:"paused"`
+	Entries    []HistoryEntry `json:"entries"`
+	NextCursor string         `json:"nextCursor"`
+}
+
+type AnalyticsDay struct {
+	Date                   string  `json:"date,omitempty"`
+	Views                  int     `json:"views"`
+	UniqueViewers          int     `json:"uniqueViewers"`
+	WatchTime              int     `json:"watchTime"`
+	AverageWatchPercentage float64 `json:"averageWatchPercentage"`
+	Likes                  int     `json:"likes"`
+	Dislikes               int     `json:"dislikes"`
+	Comments               int     `json:"comments"`
+	FollowerGains          int     `json:"followerGains"`
+}
+
+type AnalyticsResponse struct {
+	From   string         `json:"from"`
+	To     string         `json:"to"`
+	Media  *Media         `json:"media,omitempty"`
+	Totals AnalyticsDay   `json:"totals"`
+	Days   []AnalyticsDay `json:"days"`
+}
+
+type Report struct {
+	ID         string             `json:"id"`
+	ReporterID string             `json:"reporterId"`
+	TargetType string             `json:"targetType"`
+	TargetID   string             `json:"targetId"`
+	Reason     string             `json:"reason"`
+	Notes      string             `json:"notes"`
+	Status     string             `json:"status"`
+	Actions    []ModerationAction `json:"actions"`
+	CreatedAt  time.Time          `json:"createdAt"`
+	ResolvedAt *time.Time         `json:"resolvedAt"`
+}
+
+type ModerationAction struct {
+	ID          string    `json:"id"`
+	ModeratorID *string   `json:"moderatorId"`
+	Action      string    `json:"action"`
+	Notes       string    `json:"notes"`
+	CreatedAt   time.Time `json:"createdAt"`
+}
+
+type ReportQueueResponse struct {
+	Reports    []Report `json:"reports"`
+	NextCursor string   `json:"nextCursor"`
+}
+
+type ReactionResponse struct {
+	Reaction string `json:"reaction"`
+	Likes    int    `json:"likes"`
+	Dislikes int    `json:"dislikes"`
+}
+
+type FollowRequest struct {
+	ID        string    `json:"id"`
+	Requester User      `json:"requester"`
+	Target    User      `json:"target"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type Participant struct {
+	User              User       `json:"user"`
+	JoinedAt          time.Time  `json:"joinedAt"`
+	LastReadAt        *time.Time `json:"lastReadAt"`
+	LastReadMessageID string     `json:"lastReadMessageId"`
+}
+
+type Conversation struct {
+	ID            string        `json:"id"`
+	Title         string        `json:"title"`
+	IsGroup       bool          `json:"isGroup"`
+	Participants  []Participant `json:"participants"`
+	LastMessage   *Message      `json:"lastMessage"`
+	LastMessageAt time.Time     `json:"lastMessageAt"`
+	Unread        bool          `json:"unread"`
+	CreatedAt     time.Time     `json:"createdAt"`
+}
+
+type Message struct {
+	ID             string    `json:"id"`
+	ConversationID string    `json:"conversationId"`
+	Sender         User      `json:"sender"`
+	Content        string    `json:"content"`
+	Media          *Media    `json:"media"`
+	CreatedAt      time.Time `json:"createdAt"`
+}
+
+type MessagesResponse struct {
+	Messages   []Message `json:"messages"`
+	NextCursor string    `json:"nextCursor"`
+}
+
+type ReadEvent struct {
+	ConversationID string    `json:"conversationId"`
+	UserID         string    `json:"userId"`
+	MessageID      string    `json:"messageId"`
+	ReadAt         time.Time `json:"readAt"`
+}
+
+type Bookmark struct {
+	ID        string    `json:"id"`
+	Media     Media     `json:"media"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type BookmarksResponse struct {
+	Bookmarks  []Bookmark `json:"bookmarks"`
+	NextCursor string     `json:"nextCursor"`
+}
+
+type Caption struct {
+	Language  string    `json:"language"`
+	Label     string    `json:"label"`
+	URL       string    `json:"url"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+type HealthCheck struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMs float64 `json:"latencyMs"`
+}
+
+type HealthResponse struct {
+	Status string        `json:"status"`
+	Checks []HealthCheck `json:"checks,omitempty"`
 }
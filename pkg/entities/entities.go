@@ -0,0 +1,140 @@
+package entities
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+	"vilow-be/pkg/chapters"
+)
+
+const (
+	KindMention   = "mention"
+	KindHashtag   = "hashtag"
+	KindTimestamp = "timestamp"
+)
+
+// HandlePattern matches the name a mention points at: letters, digits,
+// underscores, dots and dashes, neither starting nor ending with a dot or a
+// dash. User strIds are held to it so every user can be mentioned.
+const HandlePattern = `[\p{L}\p{N}_](?:[\p{L}\p{N}_.-]*[\p{L}\p{N}_])?`
+
+var (
+	// A mention or hashtag only starts at the beginning of the text or after
+	// a character that cannot be part of a word, so e-mail addresses and
+	// URL fragments are left alone.
+	mentionPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_])@(` + HandlePattern + `)`)
+	hashtagPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_&])#([\p{L}\p{N}_]*\p{L}[\p{L}\p{N}_]*)`)
+	// Timestamps such as 12:34 become links into the video.
+	timestampPattern = regexp.MustCompile(`(?:^|[^\p{L}\p{N}_:.])((?:\d+:)?\d{1,2}:[0-5]\d)\b`)
+)
+
+// Entity is a mention, hashtag or timestamp found in a text. Start and End
+// are rune offsets of the whole token, including its @ or # sign, with End
+// exclusive. Seconds is only set on timestamps.
+type Entity struct {
+	Kind    string  `json:"kind"`
+	Start   int     `json:"start"`
+	End     int     `json:"end"`
+	Value   string  `json:"value"`
+	UserID  string  `json:"userId,omitempty"`
+	Seconds float64 `json:"seconds,omitempty"`
+}
+
+// Parse returns the mentions, hashtags and timestamps of text in order of
+// appearance. Mention values are the strId as written, hashtag values are
+// normalised.
+func Parse(text string) []Entity {
+	entities := []Entity{}
+
+	collect := func(pattern *regexp.Regexp, kind string, value func(string) string) {
+		for _, match := range pattern.FindAllStringSubmatchIndex(text, -1) {
+			// match[2] is the start of the name, the sign sits right before it.
+			start, end := match[2]-1, match[3]
+			entities = append(entities, Entity{
+				Kind:  kind,
+				Start: utf8.RuneCountInString(text[:start]),
+				End:   utf8.RuneCountInString(text[:end]),
+				Value: value(text[match[2]:match[3]]),
+			})
+		}
+	}
+
+	collect(mentionPattern, KindMention, func(name string) string { return name })
+	collect(hashtagPattern, KindHashtag, NormalizeHashtag)
+
+	for _, match := range timestampPattern.FindAllStringSubmatchIndex(text, -1) {
+		value := text[match[2]:match[3]]
+		seconds, ok := chapters.ParseTimestamp(value)
+		if !ok {
+			continue
+		}
+
+		entities = append(entities, Entity{
+			Kind:    KindTimestamp,
+			Start:   utf8.RuneCountInString(text[:match[2]]),
+			End:     utf8.RuneCountInString(text[:match[3]]),
+			Value:   value,
+			Seconds: seconds,
+		})
+	}
+
+	sort.Slice(entities, func(i, j int) bool {
+		return entities[i].Start < entities[j].Start
+	})
+
+	return entities
+}
+
+// NormalizeHashtag folds a tag, with or without its # sign, into the key
+// it is indexed under.
+func NormalizeHashtag(tag string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(tag), "#"))
+}
+
+// Mentions returns the distinct strIds mentioned in entities.
+func Mentions(entities []Entity) []string {
+	return values(entities, KindMention)
+}
+
+// Hashtags returns the distinct hashtags in entities.
+func Hashtags(entities []Entity) []string {
+	return values(entities, KindHashtag)
+}
+
+// Diff returns the values of after that are not in before, and the values
+// of before that are not in after.
+func Diff(before, after []string) (added, removed []string) {
+	return missing(after, before), missing(before, after)
+}
+
+func values(entities []Entity, kind string) []string {
+	seen := make(map[string]bool)
+	result := []string{}
+
+	for _, entity := range entities {
+		if entity.Kind != kind || seen[entity.Value] {
+			continue
+		}
+		seen[entity.Value] = true
+		result = append(result, entity.Value)
+	}
+
+	return result
+}
+
+func missing(from, in []string) []string {
+	present := make(map[string]bool, len(in))
+	for _, value := range in {
+		present[value] = true
+	}
+
+	result := []string{}
+	for _, value := range from {
+		if !present[value] {
+			result = append(result, value)
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,121 @@
+package entities
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []Entity
+	}{
+		{
+			name: "plain text",
+			text: "nothing to see",
+			want: []Entity{},
+		},
+		{
+			name: "mention and hashtag",
+			text: "@ana loves #GoLang",
+			want: []Entity{
+				{Kind: KindMention, Start: 0, End: 4, Value: "ana"},
+				{Kind: KindHashtag, Start: 11, End: 18, Value: "golang"},
+			},
+		},
+		{
+			name: "mentions do not end in a dot or dash",
+			text: "thanks @ana.b. and @joão-",
+			want: []Entity{
+				{Kind: KindMention, Start: 7, End: 13, Value: "ana.b"},
+				{Kind: KindMention, Start: 19, End: 24, Value: "joão"},
+			},
+		},
+		{
+			name: "e-mail addresses and URL fragments are not entities",
+			text: "mail ana@example.com or see page#intro and &#39;",
+			want: []Entity{},
+		},
+		{
+			name: "hashtags need a letter",
+			text: "#2024 #top10",
+			want: []Entity{
+				{Kind: KindHashtag, Start: 6, End: 12, Value: "top10"},
+			},
+		},
+		{
+			name: "timestamps",
+			text: "see 1:02:03 and (4:05), not 12:345 or v1.2:30",
+			want: []Entity{
+				{Kind: KindTimestamp, Start: 4, End: 11, Value: "1:02:03", Seconds: 3723},
+				{Kind: KindTimestamp, Start: 17, End: 21, Value: "4:05", Seconds: 245},
+			},
+		},
+		{
+			name: "offsets count runes",
+			text: "ééé #ação",
+			want: []Entity{
+				{Kind: KindHashtag, Start: 4, End: 9, Value: "ação"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse(%q) = %+v, want %+v", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMentionsAndHashtags(t *testing.T) {
+	entities := Parse("@ana @bob @ana #Go #go #news")
+
+	if got, want := Mentions(entities), []string{"ana", "bob"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Mentions() = %v, want %v", got, want)
+	}
+	if got, want := Hashtags(entities), []string{"go", "news"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Hashtags() = %v, want %v", got, want)
+	}
+}
+
+func TestNormalizeHashtag(t *testing.T) {
+	tests := map[string]string{
+		"#GoLang":  "golang",
+		" News ":   "news",
+		"ação":     "ação",
+		"#":        "",
+		"##double": "#double",
+	}
+
+	for input, want := range tests {
+		if got := NormalizeHashtag(input); got != want {
+			t.Errorf("NormalizeHashtag(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name          string
+		before, after []string
+		added         []string
+		removed       []string
+	}{
+		{name: "unchanged", before: []string{"a", "b"}, after: []string{"b", "a"}, added: []string{}, removed: []string{}},
+		{name: "from nothing", before: nil, after: []string{"a"}, added: []string{"a"}, removed: []string{}},
+		{name: "to nothing", before: []string{"a"}, after: nil, added: []string{}, removed: []string{"a"}},
+		{name: "both ways", before: []string{"a", "b"}, after: []string{"b", "c"}, added: []string{"c"}, removed: []string{"a"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			added, removed := Diff(tt.before, tt.after)
+			if !reflect.DeepEqual(added, tt.added) || !reflect.DeepEqual(removed, tt.removed) {
+				t.Errorf("Diff() = %v, %v, want %v, %v", added, removed, tt.added, tt.removed)
+			}
+		})
+	}
+}
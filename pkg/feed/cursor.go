@@ -0,0 +1,65 @@
+package feed
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+var ErrInvalidCursor = errors.New("invalid feed cursor")
+
+type cursor struct {
+	Session   int64   `json:"n"`
+	Rewatch   bool    `json:"r,omitempty"`
+	Score     float64 `json:"s"`
+	CreatedAt int64   `json:"t"`
+	ID        string  `json:"id"`
+}
+
+// precedes reports whether the cursor position comes before item.
+func (c cursor) precedes(item Scored) bool {
+	if item.Score != c.Score {
+		return item.Score < c.Score
+	}
+	if createdAt := item.CreatedAt.UnixMilli(); createdAt != c.CreatedAt {
+		return createdAt < c.CreatedAt
+	}
+	return item.ID > c.ID
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil || c.Session == 0 || c.ID == "" {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
+
+// SessionTime returns the moment the feed session behind cursorValue started,
+// or now for a first page. Candidates created after it belong to the next
+// session and should not be loaded.
+func SessionTime(cursorValue string, now time.Time) (time.Time, error) {
+	if cursorValue == "" {
+		return now, nil
+	}
+
+	c, err := decodeCursor(cursorValue)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	return time.UnixMilli(c.Session), nil
+}
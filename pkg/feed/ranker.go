@@ -0,0 +1,230 @@
+package feed
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 50
+
+	// wilsonZ is the z-score for a 95% confidence Wilson interval.
+	wilsonZ = 1.96
+)
+
+// Candidate is a media item eligible for the feed together with the
+// engagement numbers the ranker needs.
+type Candidate struct {
+	ID        string
+	OwnerID   string
+	Subjects  []string
+	Likes     int
+	Dislikes  int
+	Comments  int
+	CreatedAt time.Time
+}
+
+// Viewer holds what the ranker knows about the person the feed is built
+// for. Seen maps media IDs to the moment they were last shown to them.
+type Viewer struct {
+	ID        string
+	Subjects  []string
+	Following map[string]bool
+	Seen      map[string]time.Time
+}
+
+// Weights controls how much every signal contributes to the final score.
+// All signals are normalised to the 0..1 range before weighting.
+type Weights struct {
+	Subject   float64
+	Following float64
+	Approval  float64
+	Comments  float64
+	Freshness float64
+	HalfLife  time.Duration
+}
+
+var DefaultWeights = Weights{
+	Subject:   3,
+	Following: 2,
+	Approval:  1.5,
+	Comments:  1,
+	Freshness: 2,
+	HalfLife:  48 * time.Hour,
+}
+
+type Ranker struct {
+	weights Weights
+}
+
+func NewRanker(weights Weights) Ranker {
+	return Ranker{weights: weights}
+}
+
+type Scored struct {
+	Candidate
+	Score float64
+}
+
+type Page struct {
+	Items      []Scored
+	NextCursor string
+}
+
+// Score combines subject overlap, follow status, like ratio, comment
+// activity and freshness into a single relevance value.
+func (r Ranker) Score(viewer Viewer, candidate Candidate, now time.Time) float64 {
+	score := r.weights.Subject * subjectOverlap(viewer.Subjects, candidate.Subjects)
+
+	if viewer.Following[candidate.OwnerID] {
+		score += r.weights.Following
+	}
+
+	score += r.weights.Approval * WilsonLowerBound(candidate.Likes, candidate.Likes+candidate.Dislikes)
+
+	comments := math.Log1p(float64(candidate.Comments))
+	score += r.weights.Comments * comments / (1 + comments)
+
+	if r.weights.HalfLife > 0 {
+		age := now.Sub(candidate.CreatedAt)
+		if age < 0 {
+			age = 0
+		}
+		score += r.weights.Freshness * math.Pow(0.5, float64(age)/float64(r.weights.HalfLife))
+	}
+
+	return score
+}
+
+// Rank scores the candidates and returns the page following cursorValue.
+// The ranking time is carried in the cursor, so every page of a session is
+// cut from the same ordering even though freshness keeps decaying.
+func (r Ranker) Rank(viewer Viewer, candidates []Candidate, cursorValue string, limit int, now time.Time) (Page, error) {
+	c := cursor{Session: now.UnixMilli()}
+	if cursorValue != "" {
+		var err error
+		if c, err = decodeCursor(cursorValue); err != nil {
+			return Page{}, err
+		}
+	}
+	session := time.UnixMilli(c.Session)
+
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	scored := r.score(viewer, candidates, session, c.Rewatch)
+
+	// Once everything was seen, start over instead of serving an empty feed.
+	if len(scored) == 0 && cursorValue == "" {
+		c.Rewatch = true
+		scored = r.score(viewer, candidates, session, true)
+	}
+
+	sort.Slice(scored, func(i, j int) bool {
+		return ranksBefore(scored[i], scored[j])
+	})
+
+	start := 0
+	if c.ID != "" {
+		start = sort.Search(len(scored), func(i int) bool {
+			return c.precedes(scored[i])
+		})
+	}
+
+	end := start + limit
+	if end > len(scored) {
+		end = len(scored)
+	}
+
+	page := Page{Items: scored[start:end]}
+	if end < len(scored) {
+		last := scored[end-1]
+		page.NextCursor = encodeCursor(cursor{
+			Session:   c.Session,
+			Rewatch:   c.Rewatch,
+			Score:     last.Score,
+			CreatedAt: last.CreatedAt.UnixMilli(),
+			ID:        last.ID,
+		})
+	}
+
+	return page, nil
+}
+
+// score drops the viewer's own media, duplicates and, unless rewatching,
+// anything shown to the viewer before the session started.
+func (r Ranker) score(viewer Viewer, candidates []Candidate, session time.Time, rewatch bool) []Scored {
+	scored := make([]Scored, 0, len(candidates))
+	included := make(map[string]bool, len(candidates))
+
+	for _, candidate := range candidates {
+		if candidate.OwnerID == viewer.ID || included[candidate.ID] {
+			continue
+		}
+
+		if seenAt, ok := viewer.Seen[candidate.ID]; ok && !rewatch && seenAt.Before(session) {
+			continue
+		}
+
+		included[candidate.ID] = true
+		scored = append(scored, Scored{
+			Candidate: candidate,
+			Score:     r.Score(viewer, candidate, session),
+		})
+	}
+
+	return scored
+}
+
+func ranksBefore(a, b Scored) bool {
+	if a.Score != b.Score {
+		return a.Score > b.Score
+	}
+	if ta, tb := a.CreatedAt.UnixMilli(), b.CreatedAt.UnixMilli(); ta != tb {
+		return ta > tb
+	}
+	return a.ID < b.ID
+}
+
+// subjectOverlap is the share of the candidate's subjects the viewer is
+// interested in.
+func subjectOverlap(viewerSubjects, candidateSubjects []string) float64 {
+	if len(viewerSubjects) == 0 || len(candidateSubjects) == 0 {
+		return 0
+	}
+
+	interests := make(map[string]bool, len(viewerSubjects))
+	for _, subject := range viewerSubjects {
+		interests[strings.ToLower(subject)] = true
+	}
+
+	matches := 0
+	for _, subject := range candidateSubjects {
+		if interests[strings.ToLower(subject)] {
+			matches++
+		}
+	}
+
+	return float64(matches) / float64(len(candidateSubjects))
+}
+
+// WilsonLowerBound estimates the like ratio conservatively, so a single like
+// does not outrank hundreds of likes with a few dislikes.
+func WilsonLowerBound(positive, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+
+	n := float64(total)
+	p := float64(positive) / n
+	z2 := wilsonZ * wilsonZ
+
+	return (p + z2/(2*n) - wilsonZ*math.Sqrt((p*(1-p)+z2/(4*n))/n)) / (1 + z2/n)
+}
@@ -0,0 +1,216 @@
+package feed
+
+import (
+	"errors"
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestWilsonLowerBound(t *testing.T) {
+	tests := []struct {
+		name            string
+		positive, total int
+		want            float64
+	}{
+		{name: "no votes", positive: 0, total: 0, want: 0},
+		{name: "one like", positive: 1, total: 1, want: 0.2065},
+		{name: "one dislike", positive: 0, total: 1, want: 0},
+		{name: "mostly liked", positive: 95, total: 100, want: 0.8882},
+		{name: "split", positive: 50, total: 100, want: 0.4038},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WilsonLowerBound(tt.positive, tt.total)
+			if math.Abs(got-tt.want) > 0.0001 {
+				t.Errorf("WilsonLowerBound(%d, %d) = %.4f, want %.4f", tt.positive, tt.total, got, tt.want)
+			}
+		})
+	}
+
+	if WilsonLowerBound(1, 1) >= WilsonLowerBound(95, 100) {
+		t.Error("a single like outranks 95 likes out of 100")
+	}
+}
+
+func TestScore(t *testing.T) {
+	weights := Weights{Subject: 3, Following: 2, Approval: 1.5, Comments: 1, Freshness: 2, HalfLife: 48 * time.Hour}
+	viewer := Viewer{ID: "v", Subjects: []string{"Go", "music"}, Following: map[string]bool{"followed": true}}
+	old := testNow.Add(-1000 * 24 * time.Hour)
+
+	tests := []struct {
+		name      string
+		candidate Candidate
+		want      float64
+	}{
+		{
+			name:      "nothing in common",
+			candidate: Candidate{OwnerID: "other", CreatedAt: old},
+			want:      0,
+		},
+		{
+			name:      "half of the subjects match, case insensitive",
+			candidate: Candidate{OwnerID: "other", Subjects: []string{"go", "cooking"}, CreatedAt: old},
+			want:      1.5,
+		},
+		{
+			name:      "followed owner",
+			candidate: Candidate{OwnerID: "followed", CreatedAt: old},
+			want:      2,
+		},
+		{
+			name:      "brand new",
+			candidate: Candidate{OwnerID: "other", CreatedAt: testNow},
+			want:      2,
+		},
+		{
+			name:      "one half-life old",
+			candidate: Candidate{OwnerID: "other", CreatedAt: testNow.Add(-48 * time.Hour)},
+			want:      1,
+		},
+		{
+			name:      "created after now counts as brand new",
+			candidate: Candidate{OwnerID: "other", CreatedAt: testNow.Add(time.Hour)},
+			want:      2,
+		},
+	}
+
+	ranker := NewRanker(weights)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ranker.Score(viewer, tt.candidate, testNow)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Score() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func itemIDs(page Page) []string {
+	ids := []string{}
+	for _, item := range page.Items {
+		ids = append(ids, item.ID)
+	}
+	return ids
+}
+
+func TestRank(t *testing.T) {
+	candidates := []Candidate{
+		{ID: "a", OwnerID: "o1", Subjects: []string{"go"}, CreatedAt: testNow.Add(-time.Hour)},
+		{ID: "b", OwnerID: "o2", CreatedAt: testNow.Add(-2 * time.Hour)},
+		{ID: "c", OwnerID: "o2", CreatedAt: testNow.Add(-3 * time.Hour)},
+		{ID: "own", OwnerID: "v", Subjects: []string{"go"}, CreatedAt: testNow},
+		{ID: "a", OwnerID: "o1", Subjects: []string{"go"}, CreatedAt: testNow.Add(-time.Hour)},
+	}
+
+	tests := []struct {
+		name string
+		seen map[string]time.Time
+		want []string
+	}{
+		{
+			name: "own media and duplicates are dropped",
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "seen media are dropped",
+			seen: map[string]time.Time{"a": testNow.Add(-time.Minute)},
+			want: []string{"b", "c"},
+		},
+		{
+			name: "everything seen starts over",
+			seen: map[string]time.Time{"a": testNow.Add(-time.Minute), "b": testNow.Add(-time.Minute), "c": testNow.Add(-time.Minute)},
+			want: []string{"a", "b", "c"},
+		},
+	}
+
+	ranker := NewRanker(DefaultWeights)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			viewer := Viewer{ID: "v", Subjects: []string{"go"}, Seen: tt.seen}
+
+			page, err := ranker.Rank(viewer, candidates, "", 0, testNow)
+			if err != nil {
+				t.Fatalf("Rank() error = %v", err)
+			}
+			if got := itemIDs(page); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Rank() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRankPaging(t *testing.T) {
+	candidates := []Candidate{}
+	for i, id := range []string{"a", "b", "c", "d", "e"} {
+		candidates = append(candidates, Candidate{ID: id, OwnerID: "o", CreatedAt: testNow.Add(-time.Duration(i) * time.Hour)})
+	}
+
+	ranker := NewRanker(DefaultWeights)
+	viewer := Viewer{ID: "v"}
+
+	got := []string{}
+	cursorValue := ""
+	now := testNow
+	for {
+		page, err := ranker.Rank(viewer, candidates, cursorValue, 2, now)
+		if err != nil {
+			t.Fatalf("Rank() error = %v", err)
+		}
+		got = append(got, itemIDs(page)...)
+
+		if page.NextCursor == "" {
+			break
+		}
+		cursorValue = page.NextCursor
+
+		// Later pages are cut from the ordering of the first one.
+		now = now.Add(24 * time.Hour)
+	}
+
+	if want := []string{"a", "b", "c", "d", "e"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("paged items = %v, want %v", got, want)
+	}
+}
+
+func TestRankInvalidCursor(t *testing.T) {
+	ranker := NewRanker(DefaultWeights)
+
+	for _, value := range []string{"%%%", "e30", encodeCursor(cursor{Session: 1})} {
+		_, err := ranker.Rank(Viewer{}, nil, value, 0, testNow)
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Rank(cursor %q) error = %v, want %v", value, err, ErrInvalidCursor)
+		}
+	}
+}
+
+func TestSessionTime(t *testing.T) {
+	session := testNow.Add(-time.Hour)
+
+	tests := []struct {
+		name    string
+		cursor  string
+		want    time.Time
+		wantErr error
+	}{
+		{name: "first page", cursor: "", want: testNow},
+		{name: "later page", cursor: encodeCursor(cursor{Session: session.UnixMilli(), ID: "a"}), want: session},
+		{name: "invalid", cursor: "%%%", wantErr: ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := SessionTime(tt.cursor, testNow)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("SessionTime() error = %v, want %v", err, tt.wantErr)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("SessionTime() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"vilow-be/pkg/analytics"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+)
+
+func AnalyticsOverviewHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		report, err := parseAnalyticsRange(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		medias, err := client.Media.FindMany(
+			db.Media.UserID.Equals(authContext.UserID),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching medias", http.StatusInternalServerError)
+			return
+		}
+
+		err = utils.LoadAnalytics(r.Context(), client, report, medias, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching analytics", http.StatusInternalServerError)
+			return
+		}
+
+		writeAnalytics(w, r, report, nil, "overview")
+	}
+}
+
+func MediaAnalyticsHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report, err := parseAnalyticsRange(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		_, media, errStatusCode, err := getMediaAndAuthContext(r, client, mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		err = utils.LoadAnalytics(r.Context(), client, report, []db.MediaModel{*media}, "")
+		if err != nil {
+			apierror.Error(w, r, "Error fetching analytics", http.StatusInternalServerError)
+			return
+		}
+
+		writeAnalytics(w, r, report, media, media.ID)
+	}
+}
+
+// parseAnalyticsRange reads the from and to dates, defaulting to the last
+// four weeks up to today.
+func parseAnalyticsRange(r *http.Request) (*analytics.Report, error) {
+	to, err := parseSearchDate(r.URL.Query().Get("to"), false)
+	if err != nil {
+		return nil, errors.New("to must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	}
+	if to.IsZero() {
+		to = time.Now()
+	}
+
+	from, err := parseSearchDate(r.URL.Query().Get("from"), false)
+	if err != nil {
+		return nil, errors.New("from must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	}
+	if from.IsZero() {
+		from = to.Add(-analytics.DefaultRange + 24*time.Hour)
+	}
+
+	return analytics.NewReport(from, to)
+}
+
+func writeAnalytics(w http.ResponseWriter, r *http.Request, report *analytics.Report, media *db.MediaModel, name string) {
+	if r.URL.Query().Get("format") == "csv" {
+		filename := fmt.Sprintf("analytics-%s-%s-%s.csv", name, report.From.Format(time.DateOnly), report.To.Format(time.DateOnly))
+
+		w.Header().Set("Content-Type", "text/csv")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		if err := analytics.WriteCSV(w, report); err != nil {
+			logging.FromContext(r.Context()).Error("Error writing analytics CSV", "error", err)
+		}
+		return
+	}
+
+	response := utils.BuildAnalyticsResponse(report)
+	if media != nil {
+		mediaResponse := utils.BuildMediaResponse(media)
+		response.Media = &mediaResponse
+	}
+
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		apierror.Error(w, r, "Error converting analytics to JSON", http.StatusInternalServerError)
+		return
+	}
+}
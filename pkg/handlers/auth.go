@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+	"vilow-be/pkg/apierror"
 	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
 	"vilow-be/pkg/middleware"
 	"vilow-be/pkg/models"
 	"vilow-be/pkg/utils"
@@ -15,12 +17,17 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
-func AuthHandler(client *db.PrismaClient) http.HandlerFunc {
+func AuthHandler(client *db.PrismaClient, jwtSecret string, tokenTTL time.Duration) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var user models.User
+		var user models.Credentials
 		err := json.NewDecoder(r.Body).Decode(&user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := utils.ValidateLogin(&user); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
@@ -28,27 +35,30 @@ func AuthHandler(client *db.PrismaClient) http.HandlerFunc {
 			db.User.Email.Equals(user.Email),
 		).Exec(r.Context())
 
+		// Unknown e-mails and wrong passwords get the same answer, so the
+		// login cannot be used to find out who has an account.
 		if err != nil || existingUser == nil {
-			http.Error(w, "User not found: "+err.Error(), http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid e-mail or password"))
 			return
 		}
 
 		err = bcrypt.CompareHashAndPassword([]byte(existingUser.Password), []byte(user.Password))
 		if err != nil {
-			http.Error(w, "Wrong password", http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidCredentials, "Invalid e-mail or password"))
 			return
 		}
 
-		tokenString, err := utils.GenerateToken(existingUser.ID)
+		tokenString, err := utils.GenerateToken(existingUser.ID, jwtSecret, tokenTTL)
 		if err != nil {
-			http.Error(w, fmt.Errorf(`error generating token: %v`, err).Error(), http.StatusInternalServerError)
+			logging.FromContext(r.Context()).Error("Error generating token", "error", err)
+			apierror.Error(w, r, "Error generating token", http.StatusInternalServerError)
 			return
 		}
 
 		http.SetCookie(w, &http.Cookie{
 			Name:    "token",
 			Value:   tokenString,
-			Expires: time.Now().Add(5 * time.Hour),
+			Expires: time.Now().Add(tokenTTL),
 		})
 
 		response := &dto.LoginResponse{
@@ -59,7 +69,7 @@ func AuthHandler(client *db.PrismaClient) http.HandlerFunc {
 
 		responseBytes, err := json.Marshal(response)
 		if err != nil {
-			http.Error(w, "Error encoding the response", http.StatusInternalServerError)
+			apierror.Error(w, r, "Error encoding the response", http.StatusInternalServerError)
 			return
 		}
 
@@ -72,7 +82,7 @@ func AuthHandler(client *db.PrismaClient) http.HandlerFunc {
 func getMediaAndAuthContext(r *http.Request, client *db.PrismaClient, mediaID string) (dto.AuthContext, *db.MediaModel, int, error) {
 	authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
 	if !ok {
-		return dto.AuthContext{}, nil, http.StatusUnauthorized, errors.New("AuthContext not found in context")
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("AuthContext not found in context")
 	}
 
 	media, err := client.Media.FindUnique(
@@ -80,11 +90,11 @@ func getMediaAndAuthContext(r *http.Request, client *db.PrismaClient, mediaID st
 	).Exec(r.Context())
 
 	if err != nil {
-		return dto.AuthContext{}, nil, http.StatusUnauthorized, errors.New("media not found")
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Media not found")
 	}
 
 	if media.UserID != authContext.UserID {
-		return dto.AuthContext{}, nil, http.StatusUnauthorized, errors.New("unauthorized: You do not have permission to manipulate this media")
+		return dto.AuthContext{}, nil, http.StatusForbidden, errors.New("You do not have permission to manipulate this media")
 	}
 
 	return authContext, media, http.StatusOK, nil
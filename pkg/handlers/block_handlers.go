@@ -0,0 +1,247 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/timeline"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+)
+
+// BlockUserHandler blocks the user and drops the follows between both of
+// them, in either direction.
+func BlockUserHandler(client *db.PrismaClient, timelines *timeline.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, target, errStatusCode, err := getAuthContextAndTarget(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		_, err = client.Block.UpsertOne(
+			db.Block.BlockerIDBlockedID(
+				db.Block.BlockerID.Equals(authContext.UserID),
+				db.Block.BlockedID.Equals(target.ID),
+			),
+		).Create(
+			db.Block.Blocker.Link(
+				db.User.ID.Equals(authContext.UserID),
+			),
+			db.Block.Blocked.Link(
+				db.User.ID.Equals(target.ID),
+			),
+		).Update().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error creating block", http.StatusInternalServerError)
+			return
+		}
+
+		for _, pair := range [][2]string{{authContext.UserID, target.ID}, {target.ID, authContext.UserID}} {
+			err := removeFollow(r.Context(), client, timelines, pair[0], pair[1])
+			if err != nil && !errors.Is(err, db.ErrNotFound) {
+				apierror.Error(w, r, "Error deleting follow", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		_, err = client.FollowRequest.FindMany(
+			db.FollowRequest.Or(
+				db.FollowRequest.And(
+					db.FollowRequest.RequesterID.Equals(authContext.UserID),
+					db.FollowRequest.TargetID.Equals(target.ID),
+				),
+				db.FollowRequest.And(
+					db.FollowRequest.RequesterID.Equals(target.ID),
+					db.FollowRequest.TargetID.Equals(authContext.UserID),
+				),
+			),
+		).Delete().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error deleting follow requests", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func UnblockUserHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, target, errStatusCode, err := getAuthContextAndTarget(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		_, err = client.Block.FindUnique(
+			db.Block.BlockerIDBlockedID(
+				db.Block.BlockerID.Equals(authContext.UserID),
+				db.Block.BlockedID.Equals(target.ID),
+			),
+		).Delete().Exec(r.Context())
+
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "You have not blocked this user", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error deleting block", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func MuteUserHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, target, errStatusCode, err := getAuthContextAndTarget(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		_, err = client.Mute.UpsertOne(
+			db.Mute.MuterIDMutedID(
+				db.Mute.MuterID.Equals(authContext.UserID),
+				db.Mute.MutedID.Equals(target.ID),
+			),
+		).Create(
+			db.Mute.Muter.Link(
+				db.User.ID.Equals(authContext.UserID),
+			),
+			db.Mute.Muted.Link(
+				db.User.ID.Equals(target.ID),
+			),
+		).Update().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error creating mute", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func UnmuteUserHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, target, errStatusCode, err := getAuthContextAndTarget(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		_, err = client.Mute.FindUnique(
+			db.Mute.MuterIDMutedID(
+				db.Mute.MuterID.Equals(authContext.UserID),
+				db.Mute.MutedID.Equals(target.ID),
+			),
+		).Delete().Exec(r.Context())
+
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "You have not muted this user", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error deleting mute", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetBlocksHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		blocks, err := client.Block.FindMany(
+			db.Block.BlockerID.Equals(authContext.UserID),
+		).With(
+			db.Block.Blocked.Fetch(),
+		).OrderBy(
+			db.Block.CreatedAt.Order(db.DESC),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+			return
+		}
+
+		users := make([]dto.User, 0, len(blocks))
+		for _, block := range blocks {
+			if blocked := block.RelationsBlock.Blocked; blocked != nil {
+				users = append(users, dto.User{ID: blocked.ID, Name: blocked.Name, StrID: blocked.StrID})
+			}
+		}
+
+		err = json.NewEncoder(w).Encode(users)
+		if err != nil {
+			apierror.Error(w, r, "Error converting blocks to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetMutesHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		mutes, err := client.Mute.FindMany(
+			db.Mute.MuterID.Equals(authContext.UserID),
+		).With(
+			db.Mute.Muted.Fetch(),
+		).OrderBy(
+			db.Mute.CreatedAt.Order(db.DESC),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching mutes", http.StatusInternalServerError)
+			return
+		}
+
+		users := make([]dto.User, 0, len(mutes))
+		for _, mute := range mutes {
+			if muted := mute.RelationsMute.Muted; muted != nil {
+				users = append(users, dto.User{ID: muted.ID, Name: muted.Name, StrID: muted.StrID})
+			}
+		}
+
+		err = json.NewEncoder(w).Encode(users)
+		if err != nil {
+			apierror.Error(w, r, "Error converting mutes to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// getAuthContextAndTarget resolves the user addressed by the strId in the
+// route. Acting on oneself is rejected.
+func getAuthContextAndTarget(r *http.Request, client *db.PrismaClient) (dto.AuthContext, *db.UserModel, int, error) {
+	authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+	if !ok {
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("AuthContext not found in context")
+	}
+
+	target, err := client.User.FindUnique(
+		db.User.StrID.Equals(mux.Vars(r)["id"]),
+	).Exec(r.Context())
+	if err != nil {
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("User not found")
+	}
+
+	if target.ID == authContext.UserID {
+		return dto.AuthContext{}, nil, http.StatusBadRequest, errors.New("You cannot do this to yourself")
+	}
+
+	return authContext, target, http.StatusOK, nil
+}
@@ -0,0 +1,158 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultBookmarkLimit = 20
+	maxBookmarkLimit     = 50
+)
+
+// BookmarkMediaHandler saves a media for the current user. Bookmarks are
+// private and saving the same media twice is a no-op.
+func BookmarkMediaHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, media, errStatusCode, err := getInteractableMedia(r, client, mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		_, err = client.Bookmark.UpsertOne(
+			db.Bookmark.UserIDMediaID(
+				db.Bookmark.UserID.Equals(authContext.UserID),
+				db.Bookmark.MediaID.Equals(media.ID),
+			),
+		).Create(
+			db.Bookmark.User.Link(
+				db.User.ID.Equals(authContext.UserID),
+			),
+			db.Bookmark.Media.Link(
+				db.Media.ID.Equals(media.ID),
+			),
+		).Update().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error saving bookmark", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// DeleteBookmarkHandler removes a bookmark. It works even when the media
+// is no longer visible, so nothing gets stuck in the list.
+func DeleteBookmarkHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		_, err := client.Bookmark.FindMany(
+			db.Bookmark.UserID.Equals(authContext.UserID),
+			db.Bookmark.MediaID.Equals(mux.Vars(r)["id"]),
+		).Delete().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error deleting bookmark", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// GetBookmarksHandler lists the current user's bookmarks, newest first.
+// Media that were hidden or became invisible to the user are skipped.
+func GetBookmarksHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if limit == 0 {
+			limit = defaultBookmarkLimit
+		}
+		if limit > maxBookmarkLimit {
+			limit = maxBookmarkLimit
+		}
+
+		blockerIDs, err := utils.BlockerIDs(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+			return
+		}
+
+		visible, err := utils.VisibleMediaFilter(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching follows", http.StatusInternalServerError)
+			return
+		}
+
+		query := client.Bookmark.FindMany(
+			db.Bookmark.UserID.Equals(authContext.UserID),
+			db.Bookmark.Media.Where(
+				db.Media.Hidden.Equals(false),
+				db.Media.UserID.NotIn(blockerIDs),
+				visible,
+			),
+		).With(
+			db.Bookmark.Media.Fetch(),
+		).OrderBy(
+			db.Bookmark.CreatedAt.Order(db.DESC),
+		).Take(limit + 1)
+
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			query = query.Cursor(db.Bookmark.ID.Cursor(cursor)).Skip(1)
+		}
+
+		bookmarks, err := query.Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching bookmarks", http.StatusInternalServerError)
+			return
+		}
+
+		response := dto.BookmarksResponse{
+			Bookmarks: []dto.Bookmark{},
+		}
+
+		if len(bookmarks) > limit {
+			bookmarks = bookmarks[:limit]
+			response.NextCursor = bookmarks[limit-1].ID
+		}
+
+		for _, bookmark := range bookmarks {
+			media := utils.BuildMediaResponse(bookmark.RelationsBookmark.Media)
+			media.IsBookmarked = true
+
+			response.Bookmarks = append(response.Bookmarks, dto.Bookmark{
+				ID:        bookmark.ID,
+				Media:     media,
+				CreatedAt: bookmark.CreatedAt,
+			})
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting bookmarks to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/captions"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+)
+
+// UploadCaptionHandler stores the caption track of a language for a media,
+// replacing the previous one. SRT files are converted to WebVTT, which is
+// what browsers play.
+func UploadCaptionHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		_, media, errStatusCode, err := getMediaAndAuthContext(r, client, vars["id"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		language, err := captions.NormalizeLanguage(vars["language"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Leave room for the multipart framing and the label.
+		r.Body = http.MaxBytesReader(w, r.Body, captions.MaxSize+64<<10)
+
+		err = r.ParseMultipartForm(2 * captions.MaxSize)
+		if err != nil {
+			var maxBytesErr *http.MaxBytesError
+			if errors.As(err, &maxBytesErr) {
+				apierror.Error(w, r, captions.ErrTooLarge.Error(), http.StatusRequestEntityTooLarge)
+				return
+			}
+			apierror.Error(w, r, "Unable to process request body", http.StatusBadRequest)
+			return
+		}
+
+		file, _, err := r.FormFile("file")
+		if err != nil {
+			apierror.Error(w, r, "Unable to get file from form", http.StatusBadRequest)
+			return
+		}
+		defer file.Close()
+
+		data, err := io.ReadAll(io.LimitReader(file, captions.MaxSize+1))
+		if err != nil {
+			apierror.Error(w, r, "Unable to read caption file", http.StatusBadRequest)
+			return
+		}
+
+		vtt, err := captions.ToWebVTT(data)
+		if err != nil {
+			apierror.Error(w, r, "Invalid caption file: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		label := strings.TrimSpace(r.FormValue("label"))
+		if label == "" {
+			label = language
+		}
+
+		objectName := utils.CaptionObjectName(media.ID, language)
+		_, err = minioClient.PutObject(r.Context(), bucketName, objectName, bytes.NewReader(vtt), int64(len(vtt)), minio.PutObjectOptions{ContentType: captions.ContentType})
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error uploading caption to MinIO", "media_id", media.ID, "error", err)
+			apierror.Error(w, r, "Error uploading caption to MinIO", http.StatusInternalServerError)
+			return
+		}
+
+		caption, err := client.Caption.UpsertOne(
+			db.Caption.MediaIDLanguage(
+				db.Caption.MediaID.Equals(media.ID),
+				db.Caption.Language.Equals(language),
+			),
+		).Create(
+			db.Caption.Media.Link(
+				db.Media.ID.Equals(media.ID),
+			),
+			db.Caption.Language.Set(language),
+			db.Caption.Label.Set(label),
+			db.Caption.ObjectName.Set(objectName),
+		).Update(
+			db.Caption.Label.Set(label),
+			db.Caption.ObjectName.Set(objectName),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error saving caption", http.StatusInternalServerError)
+			return
+		}
+
+		url, err := utils.CaptionURL(r.Context(), minioClient, bucketName, caption)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error presigning caption URL", "caption_id", caption.ID, "error", err)
+			apierror.Error(w, r, "Error fetching caption from MinIO", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(utils.BuildCaptionResponse(caption, url))
+		if err != nil {
+			apierror.Error(w, r, "Error converting caption to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetCaptionsHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, media, errStatusCode, err := getVisibleMedia(r, client, mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		response, err := loadCaptions(r, client, minioClient, bucketName, media.ID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching captions", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting captions to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetCaptionHandler serves the WebVTT file of a caption track to API
+// clients. Browsers use the presigned url of the track instead.
+func GetCaptionHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		_, media, errStatusCode, err := getVisibleMedia(r, client, vars["id"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		caption, err := findCaption(r, client, media.ID, vars["language"])
+		if err != nil {
+			apierror.Error(w, r, "Caption not found", http.StatusNotFound)
+			return
+		}
+
+		object, err := minioClient.GetObject(r.Context(), bucketName, caption.ObjectName, minio.GetObjectOptions{})
+		if err != nil {
+			apierror.Error(w, r, "Error fetching caption from MinIO", http.StatusInternalServerError)
+			return
+		}
+		defer object.Close()
+
+		info, err := object.Stat()
+		if err != nil {
+			apierror.Error(w, r, "Error fetching caption from MinIO", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", captions.ContentType)
+		w.Header().Set("Content-Language", caption.Language)
+		http.ServeContent(w, r, caption.Language+".vtt", info.LastModified, object)
+	}
+}
+
+func DeleteCaptionHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+
+		_, media, errStatusCode, err := getMediaAndAuthContext(r, client, vars["id"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		caption, err := findCaption(r, client, media.ID, vars["language"])
+		if err != nil {
+			apierror.Error(w, r, "Caption not found", http.StatusNotFound)
+			return
+		}
+
+		err = minioClient.RemoveObject(r.Context(), bucketName, caption.ObjectName, minio.RemoveObjectOptions{})
+		if err != nil {
+			apierror.Error(w, r, "Error deleting caption from MinIO", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = client.Caption.FindUnique(
+			db.Caption.ID.Equals(caption.ID),
+		).Delete().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error deleting caption", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func findCaption(r *http.Request, client *db.PrismaClient, mediaID, language string) (*db.CaptionModel, error) {
+	language, err := captions.NormalizeLanguage(language)
+	if err != nil {
+		return nil, err
+	}
+
+	return client.Caption.FindUnique(
+		db.Caption.MediaIDLanguage(
+			db.Caption.MediaID.Equals(mediaID),
+			db.Caption.Language.Equals(language),
+		),
+	).Exec(r.Context())
+}
+
+func loadCaptions(r *http.Request, client *db.PrismaClient, minioClient *minio.Client, bucketName, mediaID string) ([]dto.Caption, error) {
+	tracks, err := client.Caption.FindMany(
+		db.Caption.MediaID.Equals(mediaID),
+	).OrderBy(
+		db.Caption.Language.Order(db.ASC),
+	).Exec(r.Context())
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.Caption, len(tracks))
+	for i := range tracks {
+		url, err := utils.CaptionURL(r.Context(), minioClient, bucketName, &tracks[i])
+		if err != nil {
+			return nil, err
+		}
+		response[i] = utils.BuildCaptionResponse(&tracks[i], url)
+	}
+
+	return response, nil
+}
+
+// getVisibleMedia loads a media the current user may watch. Everything else
+// is reported as not found, as GetMediaHandler does.
+func getVisibleMedia(r *http.Request, client *db.PrismaClient, mediaID string) (dto.AuthContext, *db.MediaModel, int, error) {
+	authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+	if !ok {
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("AuthContext not found in context")
+	}
+
+	media, err := client.Media.FindUnique(
+		db.Media.ID.Equals(mediaID),
+	).Exec(r.Context())
+	if err != nil {
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Media not found")
+	}
+
+	if media.UserID == authContext.UserID || utils.IsModerator(authContext.Role) {
+		return authContext, media, http.StatusOK, nil
+	}
+
+	if media.Hidden {
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Media not found")
+	}
+
+	blocked, err := utils.HasBlocked(r.Context(), client, media.UserID, authContext.UserID)
+	if err != nil {
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("Error fetching blocks")
+	} else if blocked {
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Media not found")
+	}
+
+	allowed, err := utils.CanViewMediaOf(r.Context(), client, authContext.UserID, media.UserID)
+	if err != nil {
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("Error fetching follows")
+	} else if !allowed {
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Media not found")
+	}
+
+	return authContext, media, http.StatusOK, nil
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/models"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+)
+
+const maxCommentLength = 2000
+
+func CreateCommentHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, media, errStatusCode, err := getInteractableMedia(r, client, mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		var comment models.CommentInput
+		if err := json.NewDecoder(r.Body).Decode(&comment); err != nil {
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		content := strings.TrimSpace(comment.Content)
+		if content == "" || len(content) > maxCommentLength {
+			apierror.Write(w, r, apierror.Validation(apierror.FieldError{
+				Field:   "content",
+				Message: fmt.Sprintf("must be between 1 and %d characters", maxCommentLength),
+			}))
+			return
+		}
+
+		commentEntities, err := utils.ParseEntities(r.Context(), client, content)
+		if err != nil {
+			apierror.Error(w, r, "Error parsing comment", http.StatusInternalServerError)
+			return
+		}
+
+		rawEntities, err := utils.EncodeEntities(commentEntities)
+		if err != nil {
+			apierror.Error(w, r, "Error parsing comment", http.StatusInternalServerError)
+			return
+		}
+
+		createdComment, err := client.Comment.CreateOne(
+			db.Comment.User.Link(
+				db.User.ID.Equals(authContext.UserID),
+			),
+			db.Comment.Media.Link(
+				db.Media.ID.Equals(media.ID),
+			),
+			db.Comment.Content.Set(content),
+			db.Comment.Entities.Set(rawEntities),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error creating comment", http.StatusInternalServerError)
+			return
+		}
+
+		if media.UserID != authContext.UserID {
+			err = utils.NotifyFrom(r.Context(), client, authContext.UserID, media.UserID, fmt.Sprintf("@%s commented on %s", authContext.StrID, media.Name))
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Error notifying user", "user_id", media.UserID, "error", err)
+			}
+		}
+
+		// The media owner already heard about the comment.
+		utils.NotifyMentions(r.Context(), client, authContext.UserID, commentEntities, fmt.Sprintf("@%s mentioned you in a comment on %s", authContext.StrID, media.Name), []string{media.UserID})
+
+		response := dto.Comment{
+			ID:        createdComment.ID,
+			User:      dto.User{ID: authContext.UserID, Name: authContext.Name, StrID: authContext.StrID},
+			Media:     dto.Media{ID: media.ID},
+			Content:   createdComment.Content,
+			Entities:  commentEntities,
+			CreatedAt: createdComment.CreatedAt,
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting comment to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// DeleteCommentHandler lets the author of a comment or the owner of the
+// media it was left on delete it.
+func DeleteCommentHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		vars := mux.Vars(r)
+		comment, err := client.Comment.FindFirst(
+			db.Comment.ID.Equals(vars["commentId"]),
+			db.Comment.MediaID.Equals(vars["id"]),
+		).With(
+			db.Comment.Media.Fetch(),
+		).Exec(r.Context())
+
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "Comment not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error fetching comment", http.StatusInternalServerError)
+			return
+		}
+
+		media := comment.RelationsComment.Media
+		if comment.UserID != authContext.UserID && (media == nil || media.UserID != authContext.UserID) {
+			apierror.Error(w, r, "You do not have permission to delete this comment", http.StatusForbidden)
+			return
+		}
+
+		_, err = client.Comment.FindUnique(
+			db.Comment.ID.Equals(comment.ID),
+		).Delete().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error deleting comment", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// getInteractableMedia loads a media the current user may comment on or
+// react to. Hidden media only accept their owner, and a block between the
+// viewer and the owner rules out any interaction.
+func getInteractableMedia(r *http.Request, client *db.PrismaClient, mediaID string) (dto.AuthContext, *db.MediaModel, int, error) {
+	authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+	if !ok {
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("AuthContext not found in context")
+	}
+
+	media, err := client.Media.FindUnique(
+		db.Media.ID.Equals(mediaID),
+	).Exec(r.Context())
+	if err != nil || (media.Hidden && media.UserID != authContext.UserID) {
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Media not found")
+	}
+
+	blocked, err := utils.IsBlocked(r.Context(), client, authContext.UserID, media.UserID)
+	if err != nil {
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("Error fetching blocks")
+	} else if blocked {
+		return dto.AuthContext{}, nil, http.StatusForbidden, errors.New("You cannot interact with this media")
+	}
+
+	allowed, err := utils.CanViewMediaOf(r.Context(), client, authContext.UserID, media.UserID)
+	if err != nil {
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("Error fetching follows")
+	} else if !allowed {
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Media not found")
+	}
+
+	return authContext, media, http.StatusOK, nil
+}
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"net/http"
+	"vilow-be/pkg/apierror"
+)
+
+// NotFoundHandler answers requests that match no route.
+func NotFoundHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apierror.Error(w, r, "Route not found", http.StatusNotFound)
+	}
+}
+
+// MethodNotAllowedHandler answers requests to a known route with a method
+// it does not support.
+func MethodNotAllowedHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		apierror.Error(w, r, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
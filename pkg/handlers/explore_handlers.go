@@ -0,0 +1,148 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/trending"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+)
+
+func ExploreHandler(trends *trending.Aggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		snapshot := trends.Snapshot()
+		response := dto.ExploreResponse{
+			Subjects:    []dto.SubjectCount{},
+			GeneratedAt: snapshot.GeneratedAt,
+		}
+
+		for _, subject := range snapshot.TopSubjects(limit) {
+			response.Subjects = append(response.Subjects, dto.SubjectCount{
+				Name:       subject.Name,
+				MediaCount: subject.Medias,
+				UserCount:  subject.Users,
+			})
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting subjects to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func ExploreSubjectHandler(client *db.PrismaClient, trends *trending.Aggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		subject := trending.NormalizeSubject(mux.Vars(r)["subject"])
+		snapshot := trends.Snapshot()
+
+		writeRankedMedias(w, r, client, subject, snapshot.Best(subject, limit), snapshot.GeneratedAt)
+	}
+}
+
+func TrendingHandler(client *db.PrismaClient, trends *trending.Aggregator) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		snapshot := trends.Snapshot()
+
+		writeRankedMedias(w, r, client, "", snapshot.TopTrending(limit), snapshot.GeneratedAt)
+	}
+}
+
+// writeRankedMedias loads the ranked media in snapshot order. Media deleted
+// or hidden since the snapshot was built, and media of users who blocked the
+// viewer, are skipped.
+func writeRankedMedias(w http.ResponseWriter, r *http.Request, client *db.PrismaClient, subject string, items []trending.Item, generatedAt time.Time) {
+	authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+	if !ok {
+		apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+		return
+	}
+
+	blockerIDs, err := utils.BlockerIDs(r.Context(), client, authContext.UserID)
+	if err != nil {
+		apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+		return
+	}
+
+	visible, err := utils.VisibleMediaFilter(r.Context(), client, authContext.UserID)
+	if err != nil {
+		apierror.Error(w, r, "Error fetching follows", http.StatusInternalServerError)
+		return
+	}
+
+	mediaIDs := make([]string, len(items))
+	for i, item := range items {
+		mediaIDs[i] = item.ID
+	}
+
+	medias, err := client.Media.FindMany(
+		db.Media.ID.In(mediaIDs),
+		db.Media.Hidden.Equals(false),
+		db.Media.UserID.NotIn(blockerIDs),
+		visible,
+	).Exec(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Error fetching medias", http.StatusInternalServerError)
+		return
+	}
+
+	mediasByID := make(map[string]*db.MediaModel, len(medias))
+	for i := range medias {
+		mediasByID[medias[i].ID] = &medias[i]
+	}
+
+	bookmarked, err := utils.BookmarkedMediaIDs(r.Context(), client, authContext.UserID, mediaIDs)
+	if err != nil {
+		apierror.Error(w, r, "Error fetching bookmarks", http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.RankedMediaResponse{
+		Subject:     subject,
+		Medias:      []dto.RankedMedia{},
+		GeneratedAt: generatedAt,
+	}
+
+	for _, item := range items {
+		if media, ok := mediasByID[item.ID]; ok {
+			mediaResponse := utils.BuildMediaResponse(media)
+			mediaResponse.IsBookmarked = bookmarked[media.ID]
+
+			response.Medias = append(response.Medias, dto.RankedMedia{
+				Media: mediaResponse,
+				Score: item.Score,
+			})
+		}
+	}
+
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		apierror.Error(w, r, "Error converting medias to JSON", http.StatusInternalServerError)
+		return
+	}
+}
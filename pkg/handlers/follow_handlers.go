@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/timeline"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+)
+
+func FollowUserHandler(client *db.PrismaClient, timelines *timeline.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		target, err := client.User.FindUnique(
+			db.User.StrID.Equals(mux.Vars(r)["id"]),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			return
+		}
+
+		if target.ID == authContext.UserID {
+			apierror.Error(w, r, "You cannot follow yourself", http.StatusBadRequest)
+			return
+		}
+
+		blocked, err := utils.IsBlocked(r.Context(), client, authContext.UserID, target.ID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+			return
+		} else if blocked {
+			apierror.Error(w, r, "You cannot follow this user", http.StatusForbidden)
+			return
+		}
+
+		existingFollow, err := client.Follow.FindUnique(
+			db.Follow.FollowerIDFollowingID(
+				db.Follow.FollowerID.Equals(authContext.UserID),
+				db.Follow.FollowingID.Equals(target.ID),
+			),
+		).Exec(r.Context())
+
+		if err == nil {
+			writeFollow(w, r, existingFollow, authContext, target, http.StatusOK)
+			return
+		} else if !errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "Error fetching follow", http.StatusInternalServerError)
+			return
+		}
+
+		// Private accounts approve their followers, so only a request is filed.
+		if target.IsPrivate {
+			request, err := client.FollowRequest.UpsertOne(
+				db.FollowRequest.RequesterIDTargetID(
+					db.FollowRequest.RequesterID.Equals(authContext.UserID),
+					db.FollowRequest.TargetID.Equals(target.ID),
+				),
+			).Create(
+				db.FollowRequest.Requester.Link(
+					db.User.ID.Equals(authContext.UserID),
+				),
+				db.FollowRequest.Target.Link(
+					db.User.ID.Equals(target.ID),
+				),
+			).Update().Exec(r.Context())
+			if err != nil {
+				apierror.Error(w, r, "Error creating follow request", http.StatusInternalServerError)
+				return
+			}
+
+			err = utils.NotifyFrom(r.Context(), client, authContext.UserID, target.ID, fmt.Sprintf("@%s requested to follow you", authContext.StrID))
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Error notifying user", "user_id", target.ID, "error", err)
+			}
+
+			response := dto.FollowRequest{
+				ID:        request.ID,
+				Requester: dto.User{ID: authContext.UserID, Name: authContext.Name, StrID: authContext.StrID},
+				Target:    dto.User{ID: target.ID, Name: target.Name, StrID: target.StrID},
+				CreatedAt: request.CreatedAt,
+			}
+
+			w.WriteHeader(http.StatusAccepted)
+			err = json.NewEncoder(w).Encode(response)
+			if err != nil {
+				apierror.Error(w, r, "Error converting follow request to JSON", http.StatusInternalServerError)
+				return
+			}
+			return
+		}
+
+		createdFollow, err := addFollow(r.Context(), client, timelines, authContext.UserID, target.ID)
+		if err != nil {
+			apierror.Error(w, r, "Error creating follow", http.StatusInternalServerError)
+			return
+		}
+
+		err = utils.NotifyFrom(r.Context(), client, authContext.UserID, target.ID, fmt.Sprintf("@%s started following you", authContext.StrID))
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error notifying user", "user_id", target.ID, "error", err)
+		}
+
+		writeFollow(w, r, createdFollow, authContext, target, http.StatusCreated)
+	}
+}
+
+func UnfollowUserHandler(client *db.PrismaClient, timelines *timeline.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		target, err := client.User.FindUnique(
+			db.User.StrID.Equals(mux.Vars(r)["id"]),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			return
+		}
+
+		err = removeFollow(r.Context(), client, timelines, authContext.UserID, target.ID)
+		if errors.Is(err, db.ErrNotFound) {
+			// Unfollowing an account that has not approved yet withdraws the request.
+			_, err = client.FollowRequest.FindUnique(
+				db.FollowRequest.RequesterIDTargetID(
+					db.FollowRequest.RequesterID.Equals(authContext.UserID),
+					db.FollowRequest.TargetID.Equals(target.ID),
+				),
+			).Delete().Exec(r.Context())
+		}
+
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "You are not following this user", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error deleting follow", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func FollowingFeedHandler(client *db.PrismaClient, timelines *timeline.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := timelines.Page(r.Context(), authContext.UserID, r.URL.Query().Get("cursor"), limit)
+		if errors.Is(err, timeline.ErrInvalidCursor) {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error fetching timeline", http.StatusInternalServerError)
+			return
+		}
+
+		// Muted creators are still followed, their uploads are only left out.
+		excluded, err := utils.FeedExclusions(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching timeline", http.StatusInternalServerError)
+			return
+		}
+
+		mediaIDs := make([]string, len(page.Entries))
+		for i, entry := range page.Entries {
+			mediaIDs[i] = entry.MediaID
+		}
+
+		medias, err := client.Media.FindMany(
+			db.Media.ID.In(mediaIDs),
+			db.Media.Hidden.Equals(false),
+			db.Media.UserID.NotIn(excluded),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching medias", http.StatusInternalServerError)
+			return
+		}
+
+		mediasByID := make(map[string]*db.MediaModel, len(medias))
+		for i := range medias {
+			mediasByID[medias[i].ID] = &medias[i]
+		}
+
+		response := dto.TimelineResponse{
+			Medias:     []dto.Media{},
+			NextCursor: page.NextCursor,
+		}
+
+		for _, entry := range page.Entries {
+			if media, ok := mediasByID[entry.MediaID]; ok {
+				response.Medias = append(response.Medias, utils.BuildMediaResponse(media))
+			}
+		}
+
+		if err := utils.MarkBookmarked(r.Context(), client, authContext.UserID, response.Medias); err != nil {
+			apierror.Error(w, r, "Error fetching bookmarks", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting timeline to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// addFollow makes followerID follow followingID and applies its side
+// effects on the follower count and the timeline.
+func addFollow(ctx context.Context, client *db.PrismaClient, timelines *timeline.Service, followerID, followingID string) (*db.FollowModel, error) {
+	createdFollow, err := client.Follow.CreateOne(
+		db.Follow.Follower.Link(
+			db.User.ID.Equals(followerID),
+		),
+		db.Follow.Following.Link(
+			db.User.ID.Equals(followingID),
+		),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = client.User.FindUnique(
+		db.User.ID.Equals(followingID),
+	).Update(
+		db.User.FollowerCount.Increment(1),
+	).Exec(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error updating follower count", "user_id", followingID, "error", err)
+	}
+
+	timelines.Follow(ctx, followerID, followingID)
+
+	return createdFollow, nil
+}
+
+// removeFollow deletes the follow of followerID on followingID and undoes
+// its side effects. It returns db.ErrNotFound when there was no such follow.
+func removeFollow(ctx context.Context, client *db.PrismaClient, timelines *timeline.Service, followerID, followingID string) error {
+	_, err := client.Follow.FindUnique(
+		db.Follow.FollowerIDFollowingID(
+			db.Follow.FollowerID.Equals(followerID),
+			db.Follow.FollowingID.Equals(followingID),
+		),
+	).Delete().Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	_, err = client.User.FindUnique(
+		db.User.ID.Equals(followingID),
+	).Update(
+		db.User.FollowerCount.Decrement(1),
+	).Exec(ctx)
+	if err != nil {
+		logging.FromContext(ctx).Error("Error updating follower count", "user_id", followingID, "error", err)
+	}
+
+	timelines.Unfollow(ctx, followerID, followingID)
+
+	return nil
+}
+
+func writeFollow(w http.ResponseWriter, r *http.Request, follow *db.FollowModel, follower dto.AuthContext, following *db.UserModel, status int) {
+	response := dto.Follow{
+		ID:        follow.ID,
+		Follower:  dto.User{ID: follower.UserID, Name: follower.Name, StrID: follower.StrID},
+		Following: dto.User{ID: following.ID, Name: following.Name, StrID: following.StrID},
+	}
+
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		apierror.Error(w, r, "Error converting follow to JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,245 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/timeline"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+)
+
+func GetFollowRequestsHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		requests, err := client.FollowRequest.FindMany(
+			db.FollowRequest.TargetID.Equals(authContext.UserID),
+		).With(
+			db.FollowRequest.Requester.Fetch(),
+		).OrderBy(
+			db.FollowRequest.CreatedAt.Order(db.ASC),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching follow requests", http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]dto.FollowRequest, 0, len(requests))
+		for _, request := range requests {
+			requester := request.RelationsFollowRequest.Requester
+			if requester == nil {
+				continue
+			}
+
+			response = append(response, dto.FollowRequest{
+				ID:        request.ID,
+				Requester: dto.User{ID: requester.ID, Name: requester.Name, StrID: requester.StrID},
+				Target:    dto.User{ID: authContext.UserID, Name: authContext.Name, StrID: authContext.StrID},
+				CreatedAt: request.CreatedAt,
+			})
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting follow requests to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func ApproveFollowRequestHandler(client *db.PrismaClient, timelines *timeline.Service) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, request, errStatusCode, err := getReceivedFollowRequest(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		if err := approveFollowRequest(r.Context(), client, timelines, request, authContext.StrID); err != nil {
+			apierror.Error(w, r, "Error approving follow request", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func RejectFollowRequestHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		_, request, errStatusCode, err := getReceivedFollowRequest(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		_, err = client.FollowRequest.FindUnique(
+			db.FollowRequest.ID.Equals(request.ID),
+		).Delete().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error rejecting follow request", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetFollowersHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, errStatusCode, err := getFollowListOwner(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		follows, err := client.Follow.FindMany(
+			db.Follow.FollowingID.Equals(owner.ID),
+		).With(
+			db.Follow.Follower.Fetch(),
+		).OrderBy(
+			db.Follow.CreatedAt.Order(db.DESC),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching followers", http.StatusInternalServerError)
+			return
+		}
+
+		users := make([]dto.User, 0, len(follows))
+		for _, follow := range follows {
+			if follower := follow.RelationsFollow.Follower; follower != nil {
+				users = append(users, dto.User{ID: follower.ID, Name: follower.Name, StrID: follower.StrID})
+			}
+		}
+
+		err = json.NewEncoder(w).Encode(users)
+		if err != nil {
+			apierror.Error(w, r, "Error converting followers to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetFollowingHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		owner, errStatusCode, err := getFollowListOwner(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		follows, err := client.Follow.FindMany(
+			db.Follow.FollowerID.Equals(owner.ID),
+		).With(
+			db.Follow.Following.Fetch(),
+		).OrderBy(
+			db.Follow.CreatedAt.Order(db.DESC),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching following", http.StatusInternalServerError)
+			return
+		}
+
+		users := make([]dto.User, 0, len(follows))
+		for _, follow := range follows {
+			if following := follow.RelationsFollow.Following; following != nil {
+				users = append(users, dto.User{ID: following.ID, Name: following.Name, StrID: following.StrID})
+			}
+		}
+
+		err = json.NewEncoder(w).Encode(users)
+		if err != nil {
+			apierror.Error(w, r, "Error converting following to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// approveFollowRequest turns a pending request into a follow. approverStrID
+// is used in the notification sent to the requester.
+func approveFollowRequest(ctx context.Context, client *db.PrismaClient, timelines *timeline.Service, request *db.FollowRequestModel, approverStrID string) error {
+	following, err := utils.IsFollowing(ctx, client, request.RequesterID, request.TargetID)
+	if err != nil {
+		return err
+	}
+
+	if !following {
+		if _, err := addFollow(ctx, client, timelines, request.RequesterID, request.TargetID); err != nil {
+			return err
+		}
+	}
+
+	_, err = client.FollowRequest.FindUnique(
+		db.FollowRequest.ID.Equals(request.ID),
+	).Delete().Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	err = utils.NotifyFrom(ctx, client, request.TargetID, request.RequesterID, fmt.Sprintf("@%s approved your follow request", approverStrID))
+	if err != nil {
+		logging.FromContext(ctx).Error("Error notifying user", "user_id", request.RequesterID, "error", err)
+	}
+
+	return nil
+}
+
+func getReceivedFollowRequest(r *http.Request, client *db.PrismaClient) (dto.AuthContext, *db.FollowRequestModel, int, error) {
+	authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+	if !ok {
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("AuthContext not found in context")
+	}
+
+	request, err := client.FollowRequest.FindUnique(
+		db.FollowRequest.ID.Equals(mux.Vars(r)["id"]),
+	).Exec(r.Context())
+	if err != nil || request.TargetID != authContext.UserID {
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Follow request not found")
+	}
+
+	return authContext, request, http.StatusOK, nil
+}
+
+// getFollowListOwner resolves the user whose followers or following are
+// requested. Lists of private accounts are limited to approved followers.
+func getFollowListOwner(r *http.Request, client *db.PrismaClient) (*db.UserModel, int, error) {
+	authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+	if !ok {
+		return nil, http.StatusInternalServerError, errors.New("AuthContext not found in context")
+	}
+
+	owner, err := client.User.FindUnique(
+		db.User.StrID.Equals(mux.Vars(r)["id"]),
+	).Exec(r.Context())
+	if err != nil || (owner.Hidden && owner.ID != authContext.UserID && !utils.IsModerator(authContext.Role)) {
+		return nil, http.StatusNotFound, errors.New("User not found")
+	}
+
+	blocked, err := utils.HasBlocked(r.Context(), client, owner.ID, authContext.UserID)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Error fetching blocks")
+	} else if blocked {
+		return nil, http.StatusNotFound, errors.New("User not found")
+	}
+
+	allowed, err := utils.CanViewContent(r.Context(), client, authContext.UserID, owner)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("Error fetching follows")
+	} else if !allowed {
+		return nil, http.StatusForbidden, errors.New("This account is private")
+	}
+
+	return owner, http.StatusOK, nil
+}
@@ -2,41 +2,140 @@ package handlers
 
 import (
 	"encoding/json"
-	"fmt"
+	"errors"
 	"net/http"
+	"strconv"
+	"time"
+	"vilow-be/pkg/apierror"
 	"vilow-be/pkg/dto"
+	"vilow-be/pkg/feed"
+	"vilow-be/pkg/logging"
 	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/utils"
 	"vilow-be/prisma/db"
 )
 
 func FeedHandler(client *db.PrismaClient) http.HandlerFunc {
+	ranker := feed.NewRanker(feed.DefaultWeights)
+
 	return func(w http.ResponseWriter, r *http.Request) {
 		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
 		if !ok {
-			http.Error(w, "AuthContext not found in context", http.StatusInternalServerError)
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		cursor := r.URL.Query().Get("cursor")
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		now := time.Now()
+		session, err := feed.SessionTime(cursor, now)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		viewer, err := utils.LoadFeedViewer(r.Context(), client, authContext.UserID, authContext.Subjects, now)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching data", http.StatusInternalServerError)
 			return
 		}
 
-		videoList, err := client.Media.FindMany().Exec(r.Context())
+		excluded, err := utils.FeedExclusions(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching data", http.StatusInternalServerError)
+			return
+		}
 
+		visible, err := utils.VisibleMediaFilter(r.Context(), client, authContext.UserID)
 		if err != nil {
-			http.Error(w, "Error fetching data", http.StatusUnauthorized)
+			apierror.Error(w, r, "Error fetching data", http.StatusInternalServerError)
+			return
+		}
+
+		// Media uploaded after the session started wait for the next refresh,
+		// otherwise they would shift the pages already handed out.
+		videoList, err := client.Media.FindMany(
+			db.Media.CreatedAt.Lte(session),
+			db.Media.Hidden.Equals(false),
+			db.Media.UserID.NotIn(excluded),
+			visible,
+		).With(
+			db.Media.Likes.Fetch(),
+			db.Media.Dislikes.Fetch(),
+			db.Media.Comments.Fetch(),
+		).OrderBy(
+			db.Media.CreatedAt.Order(db.DESC),
+		).Take(utils.FeedCandidatePool).Exec(r.Context())
+
+		if err != nil {
+			apierror.Error(w, r, "Error fetching data", http.StatusInternalServerError)
+			return
+		}
+
+		candidates := make([]feed.Candidate, len(videoList))
+		mediasByID := make(map[string]db.MediaModel, len(videoList))
+		for i := range videoList {
+			candidates[i] = utils.FeedCandidate(&videoList[i])
+			mediasByID[videoList[i].ID] = videoList[i]
+		}
+
+		page, err := ranker.Rank(viewer, candidates, cursor, limit, now)
+		if errors.Is(err, feed.ErrInvalidCursor) {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error ranking feed", http.StatusInternalServerError)
 			return
 		}
 
 		response := &dto.FeedResponse{
 			UserAuthData: authContext,
-			Medias:       videoList,
+			NextCursor:   page.NextCursor,
 		}
 
-		jsonData, err := json.Marshal(response)
+		served := make([]db.MediaModel, len(page.Items))
+		servedIDs := make([]string, len(page.Items))
+		for i, item := range page.Items {
+			served[i] = mediasByID[item.ID]
+			servedIDs[i] = item.ID
+		}
+
+		response.Medias, err = utils.BuildViewerMedias(r.Context(), client, authContext.UserID, served)
 		if err != nil {
-			fmt.Println("Erro ao converter para JSON:", err)
+			apierror.Error(w, r, "Error fetching bookmarks", http.StatusInternalServerError)
 			return
 		}
 
-		jsonString := string(jsonData)
+		if err := utils.RecordFeedImpressions(r.Context(), client, authContext.UserID, servedIDs); err != nil {
+			logging.FromContext(r.Context()).Error("Error recording feed impressions", "error", err)
+		}
 
-		fmt.Fprintf(w, "%s", jsonString)
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error converting feed to JSON", "error", err)
+			apierror.Error(w, r, "Error converting feed to JSON", http.StatusInternalServerError)
+			return
+		}
 	}
 }
+
+// queryLimit reads the optional "limit" query parameter. Zero means the
+// caller did not ask for a specific page size.
+func queryLimit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit <= 0 {
+		return 0, errors.New("limit must be a positive number")
+	}
+
+	return limit, nil
+}
@@ -0,0 +1,113 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/entities"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultHashtagLimit = 20
+	maxHashtagLimit     = 50
+)
+
+// GetHashtagHandler lists the newest media whose description uses a hashtag.
+func GetHashtagHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if limit == 0 {
+			limit = defaultHashtagLimit
+		}
+		if limit > maxHashtagLimit {
+			limit = maxHashtagLimit
+		}
+
+		tag := entities.NormalizeHashtag(mux.Vars(r)["tag"])
+
+		hashtag, err := client.Hashtag.FindUnique(
+			db.Hashtag.Tag.Equals(tag),
+		).Exec(r.Context())
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "Hashtag not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error fetching hashtag", http.StatusInternalServerError)
+			return
+		}
+
+		blockerIDs, err := utils.BlockerIDs(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+			return
+		}
+
+		visible, err := utils.VisibleMediaFilter(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching follows", http.StatusInternalServerError)
+			return
+		}
+
+		query := client.Media.FindMany(
+			db.Media.Hashtags.Has(tag),
+			db.Media.Hidden.Equals(false),
+			db.Media.UserID.NotIn(blockerIDs),
+			visible,
+		).OrderBy(
+			db.Media.CreatedAt.Order(db.DESC),
+		).Take(limit + 1)
+
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			query = query.Cursor(db.Media.ID.Cursor(cursor)).Skip(1)
+		}
+
+		medias, err := query.Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching medias", http.StatusInternalServerError)
+			return
+		}
+
+		response := dto.HashtagResponse{
+			Tag:        hashtag.Tag,
+			UsageCount: hashtag.UsageCount,
+			Medias:     []dto.Media{},
+		}
+
+		if len(medias) > limit {
+			medias = medias[:limit]
+			response.NextCursor = medias[limit-1].ID
+		}
+
+		for i := range medias {
+			response.Medias = append(response.Medias, utils.BuildMediaResponse(&medias[i]))
+		}
+
+		if err := utils.MarkBookmarked(r.Context(), client, authContext.UserID, response.Medias); err != nil {
+			apierror.Error(w, r, "Error fetching bookmarks", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting hashtag to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
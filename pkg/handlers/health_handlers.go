@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/health"
+	"vilow-be/pkg/logging"
+)
+
+// HealthzHandler answers as long as the process can serve requests at all.
+func HealthzHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeHealth(w, r, http.StatusOK, dto.HealthResponse{Status: health.StatusOK})
+	}
+}
+
+// ReadyzHandler runs the readiness checks and fails with 503 when one of
+// them fails or the server is shutting down. The endpoint is public, so why
+// a check failed is only logged.
+func ReadyzHandler(checker *health.Checker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		report := checker.Run(r.Context())
+
+		response := dto.HealthResponse{
+			Status: report.Status,
+			Checks: make([]dto.HealthCheck, len(report.Results)),
+		}
+		for i, result := range report.Results {
+			response.Checks[i] = dto.HealthCheck{
+				Name:      result.Name,
+				Status:    health.StatusOK,
+				LatencyMs: float64(result.Latency) / float64(time.Millisecond),
+			}
+			if result.Err != nil {
+				response.Checks[i].Status = health.StatusFailing
+				logging.FromContext(r.Context()).Error("Readiness check failed", "check", result.Name, "error", result.Err)
+			}
+		}
+
+		statusCode := http.StatusOK
+		if !report.Ready() {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		writeHealth(w, r, statusCode, response)
+	}
+}
+
+func writeHealth(w http.ResponseWriter, r *http.Request, statusCode int, response dto.HealthResponse) {
+	// Probes must always see the current state.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	err := json.NewEncoder(w).Encode(response)
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error writing health report", "error", err)
+	}
+}
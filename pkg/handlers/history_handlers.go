@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/chapters"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/models"
+	"vilow-be/pkg/playback"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultHistoryLimit = 20
+	maxHistoryLimit     = 50
+)
+
+func PlaybackHandler(client *db.PrismaClient, tracker *playback.Tracker) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var event models.PlaybackEvent
+		if err := json.NewDecoder(r.Body).Decode(&event); err != nil {
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if event.Position < 0 || event.Duration < 0 {
+			apierror.Error(w, r, "position and duration must not be negative", http.StatusBadRequest)
+			return
+		}
+
+		mediaID := mux.Vars(r)["id"]
+		media, err := client.Media.FindUnique(
+			db.Media.ID.Equals(mediaID),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Media not found", http.StatusNotFound)
+			return
+		}
+
+		// Uploads do not carry their length, so take it from the owner's
+		// player the first time it reports one. Anyone else could make it up,
+		// and chapters and analytics rely on it. Chapters saved while the
+		// length was unknown may run past the end and are cut there.
+		if media.Duration == 0 && media.UserID == authContext.UserID && event.Duration > 0 && event.Duration <= playback.MaxDuration {
+			rawChapters, _ := media.Chapters()
+			mediaChapters := chapters.Trim(utils.DecodeChapters(rawChapters), event.Duration)
+
+			rawChapters, err = utils.EncodeChapters(mediaChapters)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Error encoding chapters", "media_id", mediaID, "error", err)
+			} else {
+				_, err = client.Media.FindUnique(
+					db.Media.ID.Equals(mediaID),
+				).Update(
+					db.Media.Duration.Set(event.Duration),
+					db.Media.Chapters.Set(rawChapters),
+				).Exec(r.Context())
+				if err != nil {
+					logging.FromContext(r.Context()).Error("Error storing media duration", "media_id", mediaID, "error", err)
+				}
+			}
+		}
+
+		_, err = tracker.Record(authContext.UserID, mediaID, event.Event, event.Position, time.Now())
+		if errors.Is(err, playback.ErrUnknownEvent) {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !authContext.HistoryPaused {
+			err = utils.RecordWatchHistory(r.Context(), client, authContext.UserID, mediaID, event.Position, event.Event == playback.EventComplete)
+			if err != nil {
+				logging.FromContext(r.Context()).Error("Error recording watch history", "error", err)
+			}
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func GetHistoryHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if limit == 0 {
+			limit = defaultHistoryLimit
+		}
+		if limit > maxHistoryLimit {
+			limit = maxHistoryLimit
+		}
+
+		query := client.WatchHistory.FindMany(
+			db.WatchHistory.UserID.Equals(authContext.UserID),
+		).With(
+			db.WatchHistory.Media.Fetch(),
+		).OrderBy(
+			db.WatchHistory.WatchedAt.Order(db.DESC),
+		).Take(limit + 1)
+
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			query = query.Cursor(db.WatchHistory.ID.Cursor(cursor)).Skip(1)
+		}
+
+		entries, err := query.Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching history", http.StatusInternalServerError)
+			return
+		}
+
+		response := dto.HistoryResponse{
+			Paused:  authContext.HistoryPaused,
+			Entries: []dto.HistoryEntry{},
+		}
+
+		if len(entries) > limit {
+			entries = entries[:limit]
+			response.NextCursor = entries[limit-1].ID
+		}
+
+		for i := range entries {
+			response.Entries = append(response.Entries, utils.BuildHistoryEntry(&entries[i]))
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting history to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetHistoryEntryHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		entry, err := client.WatchHistory.FindUnique(
+			db.WatchHistory.UserIDMediaID(
+				db.WatchHistory.UserID.Equals(authContext.UserID),
+				db.WatchHistory.MediaID.Equals(mux.Vars(r)["mediaId"]),
+			),
+		).With(
+			db.WatchHistory.Media.Fetch(),
+		).Exec(r.Context())
+
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "History entry not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error fetching history entry", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(utils.BuildHistoryEntry(entry))
+		if err != nil {
+			apierror.Error(w, r, "Error converting history entry to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func DeleteHistoryHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		_, err := client.WatchHistory.FindMany(
+			db.WatchHistory.UserID.Equals(authContext.UserID),
+		).Delete().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error deleting history", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func DeleteHistoryEntryHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		_, err := client.WatchHistory.FindUnique(
+			db.WatchHistory.UserIDMediaID(
+				db.WatchHistory.UserID.Equals(authContext.UserID),
+				db.WatchHistory.MediaID.Equals(mux.Vars(r)["mediaId"]),
+			),
+		).Delete().Exec(r.Context())
+
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "History entry not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error deleting history entry", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func UpdateHistorySettingsHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var settings models.HistorySettings
+		if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		_, err := client.User.FindUnique(
+			db.User.ID.Equals(authContext.UserID),
+		).Update(
+			db.User.HistoryPaused.Set(settings.Paused),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error updating history settings", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(settings)
+		if err != nil {
+			apierror.Error(w, r, "Error converting history settings to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
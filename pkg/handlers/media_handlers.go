@@ -5,26 +5,36 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"os"
 	"regexp"
 	"strings"
 	"time"
+	"vilow-be/pkg/apierror"
 	"vilow-be/pkg/dto"
+	"vilow-be/pkg/entities"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/metrics"
 	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/search"
+	"vilow-be/pkg/timeline"
+	"vilow-be/pkg/tracing"
+	"vilow-be/pkg/utils"
 	"vilow-be/prisma/db"
 
 	"github.com/gorilla/mux"
 	"github.com/minio/minio-go/v7"
 )
 
-func UploadMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http.HandlerFunc {
+func UploadMediaHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string, index search.Index, timelines *timeline.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
-		bucketName := os.Getenv("BUCKET_NAME")
+		logger := logging.FromContext(r.Context())
+
+		// Uploads to MinIO finish even if the client goes away, but stay in
+		// the request's trace.
+		ctx := context.WithoutCancel(r.Context())
 
 		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
 		if !ok {
-			http.Error(w, "AuthContext not found in context", http.StatusInternalServerError)
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
 			return
 		}
 
@@ -33,19 +43,21 @@ func UploadMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http
 		).Exec(r.Context())
 
 		if err != nil || existingUser == nil {
-			http.Error(w, "User not found", http.StatusUnauthorized)
+			apierror.Error(w, r, "User not found", http.StatusUnauthorized)
 			return
 		}
 
+		_, parseSpan := tracing.Tracer().Start(r.Context(), "parse upload form")
 		err = r.ParseMultipartForm(1000 << 20)
+		parseSpan.End()
 		if err != nil {
-			http.Error(w, "Unable to process request body", http.StatusBadRequest)
+			apierror.Error(w, r, "Unable to process request body", http.StatusBadRequest)
 			return
 		}
 
 		file, handler, err := r.FormFile("video")
 		if err != nil {
-			http.Error(w, "Unable to get file from form", http.StatusBadRequest)
+			apierror.Error(w, r, "Unable to get file from form", http.StatusBadRequest)
 			return
 		}
 		defer file.Close()
@@ -54,6 +66,40 @@ func UploadMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http
 		description := r.FormValue("description")
 		subjects := r.Form["subjects"]
 
+		if err := utils.ValidateMedia(name, description, subjects); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		descriptionEntities, err := utils.ParseEntities(r.Context(), client, description)
+		if err != nil {
+			apierror.Error(w, r, "Error parsing description", http.StatusInternalServerError)
+			return
+		}
+
+		rawEntities, err := utils.EncodeEntities(descriptionEntities)
+		if err != nil {
+			apierror.Error(w, r, "Error parsing description", http.StatusInternalServerError)
+			return
+		}
+
+		// Hashtags double as subjects so tagged media reach the feed and explore.
+		hashtags := entities.Hashtags(descriptionEntities)
+		subjects = utils.MergeHashtagSubjects(subjects, hashtags)
+
+		_, hasChapters := r.Form["chapters"]
+		mediaChapters, err := utils.ResolveChapters(r.FormValue("chapters"), hasChapters, description, 0)
+		if err != nil {
+			apierror.Error(w, r, "Invalid chapters: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rawChapters, err := utils.EncodeChapters(mediaChapters)
+		if err != nil {
+			apierror.Error(w, r, "Error encoding chapters", http.StatusInternalServerError)
+			return
+		}
+
 		re := regexp.MustCompile(`[^a-zA-Z0-9.-]`)
 		sanitizedFilename := re.ReplaceAllString(handler.Filename, "_")
 
@@ -69,8 +115,8 @@ func UploadMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http
 		contentType := handler.Header.Get("Content-Type")
 		savedFile, err := minioClient.PutObject(ctx, bucketName, objectName, file, -1, minio.PutObjectOptions{ContentType: contentType})
 		if err != nil {
-			fmt.Printf("Error uploading video to MinIO: %s", err.Error())
-			http.Error(w, "Error uploading video to MinIO", http.StatusInternalServerError)
+			logger.Error("Error uploading video to MinIO", "error", err)
+			apierror.Error(w, r, "Error uploading video to MinIO", http.StatusInternalServerError)
 			return
 		}
 
@@ -82,25 +128,47 @@ func UploadMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http
 				db.User.ID.Equals(existingUser.ID),
 			),
 			db.Media.Subjects.Set(subjects),
+			db.Media.Entities.Set(rawEntities),
+			db.Media.Hashtags.Set(hashtags),
+			db.Media.Chapters.Set(rawChapters),
 		).Exec(r.Context())
 
 		if err != nil {
-			http.Error(w, "Error creating video in the database", http.StatusInternalServerError)
+			apierror.Error(w, r, "Error creating video in the database", http.StatusInternalServerError)
 			return
 		}
 
+		metrics.Uploads.Inc()
+
+		if err := utils.UpdateHashtagCounts(r.Context(), client, hashtags, nil); err != nil {
+			logger.Error("Error updating hashtags", "media_id", createdMedia.ID, "error", err)
+		}
+
+		utils.NotifyMentions(r.Context(), client, authContext.UserID, descriptionEntities, fmt.Sprintf("@%s mentioned you in %s", authContext.StrID, createdMedia.Name), nil)
+
+		if err := index.Upsert(utils.MediaDocument(createdMedia)); err != nil {
+			logger.Error("Error indexing media", "media_id", createdMedia.ID, "error", err)
+		}
+
+		timelines.Publish(r.Context(), createdMedia)
+
 		w.WriteHeader(http.StatusCreated)
-		err = json.NewEncoder(w).Encode(createdMedia)
+		err = json.NewEncoder(w).Encode(utils.BuildMediaResponse(createdMedia))
 
 		if err != nil {
-			http.Error(w, "Error converting video to JSON", http.StatusInternalServerError)
+			apierror.Error(w, r, "Error converting video to JSON", http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
-func GetMediaHandler(client *db.PrismaClient) http.HandlerFunc {
+func GetMediaHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
 
 		vars := mux.Vars(r)
 		mediaID := vars["id"]
@@ -110,34 +178,69 @@ func GetMediaHandler(client *db.PrismaClient) http.HandlerFunc {
 		).With(
 			db.Media.Likes.Fetch(),
 			db.Media.Dislikes.Fetch(),
-			db.Media.Comments.Fetch(),
+			db.Media.Comments.Fetch(
+				db.Comment.Hidden.Equals(false),
+			),
 		).Exec(r.Context())
 
+		// Hidden media stay visible to their owner and to moderators.
+		if err != nil || (media.Hidden && media.UserID != authContext.UserID && !utils.IsModerator(authContext.Role)) {
+			apierror.Error(w, r, "Media not found", http.StatusNotFound)
+			return
+		}
+
+		blocked, err := utils.HasBlocked(r.Context(), client, media.UserID, authContext.UserID)
 		if err != nil {
-			http.Error(w, "Media not found", http.StatusNotFound)
+			apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+			return
+		} else if blocked {
+			apierror.Error(w, r, "Media not found", http.StatusNotFound)
 			return
 		}
 
-		err = json.NewEncoder(w).Encode(media)
+		allowed, err := utils.CanViewMediaOf(r.Context(), client, authContext.UserID, media.UserID)
 		if err != nil {
-			http.Error(w, "Error converting media to JSON", http.StatusInternalServerError)
+			apierror.Error(w, r, "Error fetching follows", http.StatusInternalServerError)
+			return
+		} else if !allowed && !utils.IsModerator(authContext.Role) {
+			apierror.Error(w, r, "Media not found", http.StatusNotFound)
+			return
+		}
+
+		response, err := utils.BuildViewerMedias(r.Context(), client, authContext.UserID, []db.MediaModel{*media})
+		if err != nil {
+			apierror.Error(w, r, "Error fetching bookmarks", http.StatusInternalServerError)
+			return
+		}
+
+		response[0].Captions, err = loadCaptions(r, client, minioClient, bucketName, media.ID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching captions", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(response[0])
+		if err != nil {
+			apierror.Error(w, r, "Error converting media to JSON", http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
-func UpdateMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http.HandlerFunc {
+func UpdateMediaHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string, index search.Index) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.FromContext(r.Context())
 
-		ctx := context.Background()
-		bucketName := os.Getenv("BUCKET_NAME")
+		// Uploads to MinIO finish even if the client goes away, but stay in
+		// the request's trace.
+		ctx := context.WithoutCancel(r.Context())
 
 		vars := mux.Vars(r)
 		mediaID := vars["id"]
 
-		_, media, errStatusCode, err := getMediaAndAuthContext(r, client, mediaID)
+		authContext, media, errStatusCode, err := getMediaAndAuthContext(r, client, mediaID)
 		if err != nil {
-			http.Error(w, err.Error(), errStatusCode)
+			apierror.Error(w, r, err.Error(), errStatusCode)
 			return
 		}
 
@@ -145,6 +248,47 @@ func UpdateMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http
 		description := r.FormValue("description")
 		subjects := r.Form["subjects"]
 
+		if err := utils.ValidateMedia(name, description, subjects); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		descriptionEntities, err := utils.ParseEntities(r.Context(), client, description)
+		if err != nil {
+			apierror.Error(w, r, "Error parsing description", http.StatusInternalServerError)
+			return
+		}
+
+		rawEntities, err := utils.EncodeEntities(descriptionEntities)
+		if err != nil {
+			apierror.Error(w, r, "Error parsing description", http.StatusInternalServerError)
+			return
+		}
+
+		hashtags := entities.Hashtags(descriptionEntities)
+		subjects = utils.MergeHashtagSubjects(subjects, hashtags)
+
+		// A new video file may have another length, so the stored duration
+		// only holds chapters to it when the file stays. Otherwise it is
+		// forgotten until the owner's player reports the new one.
+		duration := media.Duration
+		if r.MultipartForm != nil && len(r.MultipartForm.File["video"]) > 0 {
+			duration = 0
+		}
+
+		_, hasChapters := r.Form["chapters"]
+		mediaChapters, err := utils.ResolveChapters(r.FormValue("chapters"), hasChapters, description, duration)
+		if err != nil {
+			apierror.Error(w, r, "Invalid chapters: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		rawChapters, err := utils.EncodeChapters(mediaChapters)
+		if err != nil {
+			apierror.Error(w, r, "Error encoding chapters", http.StatusInternalServerError)
+			return
+		}
+
 		file, handler, err := r.FormFile("video")
 		if err == nil {
 			defer file.Close()
@@ -164,8 +308,8 @@ func UpdateMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http
 			contentType := handler.Header.Get("Content-Type")
 			_, err = minioClient.PutObject(ctx, bucketName, objectName, file, -1, minio.PutObjectOptions{ContentType: contentType})
 			if err != nil {
-				fmt.Printf("Error uploading video to MinIO: %s", err.Error())
-				http.Error(w, "Error uploading video to MinIO", http.StatusInternalServerError)
+				logger.Error("Error uploading video to MinIO", "error", err)
+				apierror.Error(w, r, "Error uploading video to MinIO", http.StatusInternalServerError)
 				return
 			}
 
@@ -179,70 +323,60 @@ func UpdateMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http
 			db.Media.Description.Set(description),
 			db.Media.Subjects.Set(subjects),
 			db.Media.Path.Set(media.Path),
+			db.Media.Entities.Set(rawEntities),
+			db.Media.Hashtags.Set(hashtags),
+			db.Media.Chapters.Set(rawChapters),
+			db.Media.Duration.Set(duration),
 		).Exec(r.Context())
 
 		if err != nil {
-			http.Error(w, "Error updating media in the database", http.StatusInternalServerError)
+			apierror.Error(w, r, "Error updating media in the database", http.StatusInternalServerError)
 			return
 		}
 
-		err = json.NewEncoder(w).Encode(updatedMedia)
+		added, removed := entities.Diff(media.Hashtags, hashtags)
+		if err := utils.UpdateHashtagCounts(r.Context(), client, added, removed); err != nil {
+			logger.Error("Error updating hashtags", "media_id", updatedMedia.ID, "error", err)
+		}
+
+		// Users mentioned before the edit were already notified.
+		previousEntities, _ := media.Entities()
+		utils.NotifyMentions(r.Context(), client, authContext.UserID, descriptionEntities, fmt.Sprintf("@%s mentioned you in %s", authContext.StrID, updatedMedia.Name), utils.MentionedUserIDs(utils.DecodeEntities(previousEntities)))
+
+		if err := utils.IndexMedia(index, updatedMedia); err != nil {
+			logger.Error("Error indexing media", "media_id", updatedMedia.ID, "error", err)
+		}
+
+		err = json.NewEncoder(w).Encode(utils.BuildMediaResponse(updatedMedia))
 		if err != nil {
-			http.Error(w, "Error converting media to JSON", http.StatusInternalServerError)
+			apierror.Error(w, r, "Error converting media to JSON", http.StatusInternalServerError)
 			return
 		}
 	}
 }
 
-func DeleteMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http.HandlerFunc {
+func DeleteMediaHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string, index search.Index) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.FromContext(r.Context())
+
 		mediaID := mux.Vars(r)["id"]
-		bucketName := os.Getenv("BUCKET_NAME")
 
 		_, media, errStatusCode, err := getMediaAndAuthContext(r, client, mediaID)
 
 		if err != nil {
-			http.Error(w, err.Error(), errStatusCode)
-			return
-		}
-
-		objectName := strings.TrimPrefix(media.Path, "http://localhost:9000/vilow-videos/")
-		err = minioClient.RemoveObject(r.Context(), bucketName, objectName, minio.RemoveObjectOptions{})
-		if err != nil {
-			http.Error(w, "Error deleting media file from MinIO: "+err.Error(), http.StatusInternalServerError)
-			return
-		}
-
-		_, err = client.Like.FindMany(
-			db.Like.MediaID.Equals(mediaID),
-		).Delete().Exec(r.Context())
-		if err != nil {
-			http.Error(w, "Error deleting likes associated with media", http.StatusInternalServerError)
-			return
-		}
-
-		_, err = client.Dislike.FindMany(
-			db.Dislike.MediaID.Equals(mediaID),
-		).Delete().Exec(r.Context())
-		if err != nil {
-			http.Error(w, "Error deleting dislikes associated with media", http.StatusInternalServerError)
+			apierror.Error(w, r, err.Error(), errStatusCode)
 			return
 		}
 
-		_, err = client.Comment.FindMany(
-			db.Comment.MediaID.Equals(mediaID),
-		).Delete().Exec(r.Context())
+		err = utils.DeleteMedia(r.Context(), client, minioClient, bucketName, media)
 		if err != nil {
-			http.Error(w, "Error deleting comments associated with media", http.StatusInternalServerError)
+			logger.Error("Error deleting media", "media_id", mediaID, "error", err)
+			apierror.Error(w, r, "Error deleting media", http.StatusInternalServerError)
 			return
 		}
 
-		_, err = client.Media.FindUnique(
-			db.Media.ID.Equals(mediaID),
-		).Delete().Exec(r.Context())
-		if err != nil {
-			http.Error(w, "Error deleting media from database", http.StatusInternalServerError)
-			return
+		if err := index.Delete(search.KindMedia, mediaID); err != nil {
+			logger.Error("Error removing media from the search index", "media_id", mediaID, "error", err)
 		}
 
 		w.WriteHeader(http.StatusNoContent)
@@ -250,13 +384,18 @@ func DeleteMediaHandler(client *db.PrismaClient, minioClient *minio.Client) http
 	}
 }
 
+// GetMediasTimelineHandler lists the media on the user's subjects in upload
+// order, wrapping around at the end. It is deliberately not ranked like
+// FeedHandler: pages are keyed by lastMediaID, so an order that moves with
+// likes and age would skip or repeat media between pages, and clients use it
+// to go through everything rather than for the best first.
 func GetMediasTimelineHandler(client *db.PrismaClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
+		ctx := r.Context()
 
 		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
 		if !ok {
-			http.Error(w, "AuthContext not found in context", http.StatusInternalServerError)
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
 			return
 		}
 
@@ -265,7 +404,19 @@ func GetMediasTimelineHandler(client *db.PrismaClient) http.HandlerFunc {
 		).Exec(r.Context())
 
 		if err != nil || existingUser == nil {
-			http.Error(w, "User not found: "+existingUser.Email, http.StatusUnauthorized)
+			apierror.Error(w, r, "User not found", http.StatusUnauthorized)
+			return
+		}
+
+		excluded, err := utils.FeedExclusions(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching medias", http.StatusInternalServerError)
+			return
+		}
+
+		visible, err := utils.VisibleMediaFilter(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching medias", http.StatusInternalServerError)
 			return
 		}
 
@@ -280,6 +431,9 @@ func GetMediasTimelineHandler(client *db.PrismaClient) http.HandlerFunc {
 				FindMany(
 					db.Media.Subjects.HasSome(existingUser.Subjects),
 					db.Media.ID.Gt(lastMediaID),
+					db.Media.Hidden.Equals(false),
+					db.Media.UserID.NotIn(excluded),
+					visible,
 				).
 				OrderBy(
 					db.Media.ID.Order(db.ASC),
@@ -294,6 +448,9 @@ func GetMediasTimelineHandler(client *db.PrismaClient) http.HandlerFunc {
 				Media.
 				FindMany(
 					db.Media.Subjects.HasSome(existingUser.Subjects),
+					db.Media.Hidden.Equals(false),
+					db.Media.UserID.NotIn(excluded),
+					visible,
 				).
 				OrderBy(
 					db.Media.ID.Order(db.ASC),
@@ -303,7 +460,7 @@ func GetMediasTimelineHandler(client *db.PrismaClient) http.HandlerFunc {
 		}
 
 		if err != nil {
-			http.Error(w, "Error fetching medias", http.StatusInternalServerError)
+			apierror.Error(w, r, "Error fetching medias", http.StatusInternalServerError)
 			return
 		}
 
@@ -311,7 +468,11 @@ func GetMediasTimelineHandler(client *db.PrismaClient) http.HandlerFunc {
 			lastMediaID = ""
 			medias, err = client.
 				Media.
-				FindMany().
+				FindMany(
+					db.Media.Hidden.Equals(false),
+					db.Media.UserID.NotIn(excluded),
+					visible,
+				).
 				Take(pageSize).
 				Skip(0).
 				OrderBy(
@@ -319,16 +480,22 @@ func GetMediasTimelineHandler(client *db.PrismaClient) http.HandlerFunc {
 				).Exec(ctx)
 
 			if err != nil {
-				http.Error(w, "Error fetching medias", http.StatusInternalServerError)
+				apierror.Error(w, r, "Error fetching medias", http.StatusInternalServerError)
 				return
 			}
 		}
 
+		response, err := utils.BuildViewerMedias(r.Context(), client, authContext.UserID, medias)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching bookmarks", http.StatusInternalServerError)
+			return
+		}
+
 		w.WriteHeader(http.StatusOK)
-		err = json.NewEncoder(w).Encode(medias)
+		err = json.NewEncoder(w).Encode(response)
 
 		if err != nil {
-			http.Error(w, "Error converting medias to JSON", http.StatusInternalServerError)
+			apierror.Error(w, r, "Error converting medias to JSON", http.StatusInternalServerError)
 			return
 		}
 	}
@@ -0,0 +1,631 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/messaging"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/models"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+const (
+	defaultMessageLimit = 30
+	maxMessageLimit     = 100
+	maxMessageLength    = 4000
+
+	// streamHeartbeat keeps idle streaming connections from being closed by
+	// proxies along the way.
+	streamHeartbeat = 30 * time.Second
+)
+
+func CreateConversationHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var conversation models.Conversation
+		if err := json.NewDecoder(r.Body).Decode(&conversation); err != nil {
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		strIDs := []string{}
+		seen := map[string]bool{authContext.StrID: true}
+		for _, strID := range conversation.Participants {
+			strID = strings.TrimPrefix(strings.TrimSpace(strID), "@")
+			if strID != "" && !seen[strID] {
+				seen[strID] = true
+				strIDs = append(strIDs, strID)
+			}
+		}
+
+		if len(strIDs) == 0 || len(strIDs)+1 > models.MaxConversationSize {
+			apierror.Write(w, r, apierror.Validation(apierror.FieldError{
+				Field:   "participants",
+				Message: fmt.Sprintf("a conversation needs between 2 and %d participants", models.MaxConversationSize),
+			}))
+			return
+		}
+
+		users, err := client.User.FindMany(
+			db.User.StrID.In(strIDs),
+			db.User.Hidden.Equals(false),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching users", http.StatusInternalServerError)
+			return
+		} else if len(users) != len(strIDs) {
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			return
+		}
+
+		for _, user := range users {
+			blocked, err := utils.IsBlocked(r.Context(), client, authContext.UserID, user.ID)
+			if err != nil {
+				apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+				return
+			} else if blocked {
+				apierror.Error(w, r, fmt.Sprintf("You cannot message @%s", user.StrID), http.StatusForbidden)
+				return
+			}
+		}
+
+		isGroup := len(users) > 1
+
+		// There is only ever one 1:1 conversation between two users.
+		if !isGroup {
+			existing, err := client.Conversation.FindFirst(
+				db.Conversation.IsGroup.Equals(false),
+				db.Conversation.Participants.Some(
+					db.Participant.UserID.Equals(authContext.UserID),
+				),
+				db.Conversation.Participants.Some(
+					db.Participant.UserID.Equals(users[0].ID),
+				),
+			).Exec(r.Context())
+			if err == nil {
+				writeConversation(w, r, client, existing.ID, authContext.UserID, http.StatusOK)
+				return
+			} else if !errors.Is(err, db.ErrNotFound) {
+				apierror.Error(w, r, "Error fetching conversations", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		title := ""
+		if isGroup {
+			title = strings.TrimSpace(conversation.Title)
+		}
+
+		userIDs := []string{authContext.UserID}
+		for _, user := range users {
+			userIDs = append(userIDs, user.ID)
+		}
+
+		// The id is chosen up front so the participants can be created in
+		// the same transaction and no conversation is left without them.
+		conversationID := utils.NewID()
+		ops := []transaction.Param{
+			client.Conversation.CreateOne(
+				db.Conversation.ID.Set(conversationID),
+				db.Conversation.Title.Set(title),
+				db.Conversation.IsGroup.Set(isGroup),
+			).Tx(),
+		}
+		for _, userID := range userIDs {
+			ops = append(ops, client.Participant.CreateOne(
+				db.Participant.Conversation.Link(
+					db.Conversation.ID.Equals(conversationID),
+				),
+				db.Participant.User.Link(
+					db.User.ID.Equals(userID),
+				),
+			).Tx())
+		}
+
+		if err := client.Prisma.Transaction(ops...).Exec(r.Context()); err != nil {
+			apierror.Error(w, r, "Error creating conversation", http.StatusInternalServerError)
+			return
+		}
+
+		writeConversation(w, r, client, conversationID, authContext.UserID, http.StatusCreated)
+	}
+}
+
+func GetConversationsHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		conversations, err := client.Conversation.FindMany(
+			db.Conversation.Participants.Some(
+				db.Participant.UserID.Equals(authContext.UserID),
+			),
+		).With(
+			conversationFetch()...,
+		).OrderBy(
+			db.Conversation.LastMessageAt.Order(db.DESC),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching conversations", http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]dto.Conversation, len(conversations))
+		for i := range conversations {
+			response[i] = utils.BuildConversationResponse(&conversations[i], authContext.UserID)
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting conversations to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// GetMessagesHandler pages through a conversation from the newest message
+// backwards. Messages of users the viewer blocked are left out.
+func GetMessagesHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, conversation, errStatusCode, err := getConversationAndAuthContext(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if limit == 0 {
+			limit = defaultMessageLimit
+		}
+		if limit > maxMessageLimit {
+			limit = maxMessageLimit
+		}
+
+		blockedIDs, err := utils.BlockedIDs(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+			return
+		}
+
+		query := client.Message.FindMany(
+			db.Message.ConversationID.Equals(conversation.ID),
+			db.Message.SenderID.NotIn(blockedIDs),
+		).With(
+			db.Message.Sender.Fetch(),
+			db.Message.Media.Fetch(),
+		).OrderBy(
+			db.Message.CreatedAt.Order(db.DESC),
+		).Take(limit + 1)
+
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			query = query.Cursor(db.Message.ID.Cursor(cursor)).Skip(1)
+		}
+
+		messages, err := query.Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching messages", http.StatusInternalServerError)
+			return
+		}
+
+		response := dto.MessagesResponse{
+			Messages: []dto.Message{},
+		}
+
+		if len(messages) > limit {
+			messages = messages[:limit]
+			response.NextCursor = messages[limit-1].ID
+		}
+
+		for i := range messages {
+			response.Messages = append(response.Messages, utils.BuildMessageResponse(&messages[i]))
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting messages to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func SendMessageHandler(client *db.PrismaClient, hub *messaging.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, conversation, errStatusCode, err := getConversationAndAuthContext(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		var message models.Message
+		if err := json.NewDecoder(r.Body).Decode(&message); err != nil {
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		content := strings.TrimSpace(message.Content)
+		if (content == "" && message.MediaID == "") || len(content) > maxMessageLength {
+			apierror.Write(w, r, apierror.Validation(apierror.FieldError{
+				Field:   "content",
+				Message: fmt.Sprintf("a message needs a media or between 1 and %d characters", maxMessageLength),
+			}))
+			return
+		}
+
+		participantIDs := utils.ConversationParticipantIDs(conversation)
+
+		if !conversation.IsGroup {
+			for _, userID := range participantIDs {
+				if userID == authContext.UserID {
+					continue
+				}
+
+				blocked, err := utils.IsBlocked(r.Context(), client, authContext.UserID, userID)
+				if err != nil {
+					apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+					return
+				} else if blocked {
+					apierror.Error(w, r, "You cannot message this user", http.StatusForbidden)
+					return
+				}
+			}
+		}
+
+		optional := []db.MessageSetParam{}
+		if message.MediaID != "" {
+			errStatusCode, err := checkSharedMedia(r, client, authContext, message.MediaID)
+			if err != nil {
+				apierror.Error(w, r, err.Error(), errStatusCode)
+				return
+			}
+
+			optional = append(optional, db.Message.Media.Link(
+				db.Media.ID.Equals(message.MediaID),
+			))
+		}
+
+		createdMessage, err := client.Message.CreateOne(
+			db.Message.Conversation.Link(
+				db.Conversation.ID.Equals(conversation.ID),
+			),
+			db.Message.Sender.Link(
+				db.User.ID.Equals(authContext.UserID),
+			),
+			db.Message.Content.Set(content),
+			optional...,
+		).With(
+			db.Message.Sender.Fetch(),
+			db.Message.Media.Fetch(),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error sending message", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = client.Conversation.FindUnique(
+			db.Conversation.ID.Equals(conversation.ID),
+		).Update(
+			db.Conversation.LastMessageAt.Set(createdMessage.CreatedAt),
+		).Exec(r.Context())
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error updating conversation", "conversation_id", conversation.ID, "error", err)
+		}
+
+		// Senders have read their own message.
+		if _, err := markRead(r, client, conversation.ID, authContext.UserID, createdMessage); err != nil {
+			logging.FromContext(r.Context()).Error("Error updating read receipt", "conversation_id", conversation.ID, "error", err)
+		}
+
+		response := utils.BuildMessageResponse(createdMessage)
+
+		recipients, err := streamRecipients(r, client, authContext.UserID, participantIDs)
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error fetching blocks", "error", err)
+		}
+		hub.Publish(recipients, messaging.Event{Type: messaging.EventMessage, Data: response})
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting message to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// MarkConversationReadHandler moves the read receipt of the current user up
+// to the given message, or to the newest one when none is given.
+func MarkConversationReadHandler(client *db.PrismaClient, hub *messaging.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, conversation, errStatusCode, err := getConversationAndAuthContext(r, client)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		var receipt models.ReadReceipt
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&receipt); err != nil {
+				apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		var message *db.MessageModel
+		if receipt.MessageID != "" {
+			message, err = client.Message.FindUnique(
+				db.Message.ID.Equals(receipt.MessageID),
+			).Exec(r.Context())
+			if err == nil && message.ConversationID != conversation.ID {
+				err = db.ErrNotFound
+			}
+		} else {
+			message, err = client.Message.FindFirst(
+				db.Message.ConversationID.Equals(conversation.ID),
+			).OrderBy(
+				db.Message.CreatedAt.Order(db.DESC),
+			).Exec(r.Context())
+		}
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "Message not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error fetching message", http.StatusInternalServerError)
+			return
+		}
+
+		moved, err := markRead(r, client, conversation.ID, authContext.UserID, message)
+		if err != nil {
+			apierror.Error(w, r, "Error updating read receipt", http.StatusInternalServerError)
+			return
+		} else if !moved {
+			// The receipt is already past this message, so there is
+			// nothing new to tell the other participants.
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		recipients, err := streamRecipients(r, client, authContext.UserID, utils.ConversationParticipantIDs(conversation))
+		if err != nil {
+			logging.FromContext(r.Context()).Error("Error fetching blocks", "error", err)
+		}
+		hub.Publish(recipients, messaging.Event{
+			Type: messaging.EventRead,
+			Data: dto.ReadEvent{
+				ConversationID: conversation.ID,
+				UserID:         authContext.UserID,
+				MessageID:      message.ID,
+				ReadAt:         message.CreatedAt,
+			},
+		})
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// StreamMessagesHandler holds a server-sent events connection open and
+// pushes the messages and read receipts of the current user's
+// conversations as they happen.
+func StreamMessagesHandler(hub *messaging.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			apierror.Error(w, r, "Streaming is not supported", http.StatusInternalServerError)
+			return
+		}
+
+		// The stream outlives the server's write timeout by design, the
+		// heartbeat is what detects dead connections.
+		if err := http.NewResponseController(w).SetWriteDeadline(time.Time{}); err != nil {
+			logging.FromContext(r.Context()).Error("Error lifting write deadline of message stream", "error", err)
+		}
+
+		events, unsubscribe := hub.Subscribe(authContext.UserID)
+		defer unsubscribe()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.WriteHeader(http.StatusOK)
+		fmt.Fprint(w, ": connected\n\n")
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(streamHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+				flusher.Flush()
+			case event, ok := <-events:
+				if !ok {
+					return
+				}
+
+				data, err := json.Marshal(event.Data)
+				if err != nil {
+					logging.FromContext(r.Context()).Error("Error converting event to JSON", "event", event.Type, "error", err)
+					continue
+				}
+
+				fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data)
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+func conversationFetch() []db.ConversationRelationWith {
+	return []db.ConversationRelationWith{
+		db.Conversation.Participants.Fetch().With(
+			db.Participant.User.Fetch(),
+		),
+		db.Conversation.Messages.Fetch().OrderBy(
+			db.Message.CreatedAt.Order(db.DESC),
+		).Take(1).With(
+			db.Message.Sender.Fetch(),
+		),
+	}
+}
+
+func writeConversation(w http.ResponseWriter, r *http.Request, client *db.PrismaClient, conversationID, viewerID string, status int) {
+	conversation, err := client.Conversation.FindUnique(
+		db.Conversation.ID.Equals(conversationID),
+	).With(
+		conversationFetch()...,
+	).Exec(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Error fetching conversation", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(utils.BuildConversationResponse(conversation, viewerID))
+	if err != nil {
+		apierror.Error(w, r, "Error converting conversation to JSON", http.StatusInternalServerError)
+		return
+	}
+}
+
+// getConversationAndAuthContext loads the conversation in the route with
+// its participants. Users outside of it get a 404.
+func getConversationAndAuthContext(r *http.Request, client *db.PrismaClient) (dto.AuthContext, *db.ConversationModel, int, error) {
+	authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+	if !ok {
+		return dto.AuthContext{}, nil, http.StatusInternalServerError, errors.New("AuthContext not found in context")
+	}
+
+	conversation, err := client.Conversation.FindUnique(
+		db.Conversation.ID.Equals(mux.Vars(r)["id"]),
+	).With(
+		db.Conversation.Participants.Fetch(),
+	).Exec(r.Context())
+	if err != nil {
+		return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Conversation not found")
+	}
+
+	for _, userID := range utils.ConversationParticipantIDs(conversation) {
+		if userID == authContext.UserID {
+			return authContext, conversation, http.StatusOK, nil
+		}
+	}
+
+	return dto.AuthContext{}, nil, http.StatusNotFound, errors.New("Conversation not found")
+}
+
+// checkSharedMedia makes sure the sender may see the media they share.
+func checkSharedMedia(r *http.Request, client *db.PrismaClient, authContext dto.AuthContext, mediaID string) (int, error) {
+	media, err := client.Media.FindUnique(
+		db.Media.ID.Equals(mediaID),
+	).Exec(r.Context())
+	if err != nil || media.Hidden {
+		return http.StatusNotFound, errors.New("Media not found")
+	}
+
+	blocked, err := utils.HasBlocked(r.Context(), client, media.UserID, authContext.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Error fetching blocks")
+	} else if blocked {
+		return http.StatusNotFound, errors.New("Media not found")
+	}
+
+	allowed, err := utils.CanViewMediaOf(r.Context(), client, authContext.UserID, media.UserID)
+	if err != nil {
+		return http.StatusInternalServerError, errors.New("Error fetching follows")
+	} else if !allowed {
+		return http.StatusNotFound, errors.New("Media not found")
+	}
+
+	return http.StatusOK, nil
+}
+
+// markRead moves the read receipt of the user up to the message. Receipts
+// never move back, so marking an older message read changes nothing and
+// reports false.
+func markRead(r *http.Request, client *db.PrismaClient, conversationID, userID string, message *db.MessageModel) (bool, error) {
+	participant, err := client.Participant.FindUnique(
+		db.Participant.ConversationIDUserID(
+			db.Participant.ConversationID.Equals(conversationID),
+			db.Participant.UserID.Equals(userID),
+		),
+	).Exec(r.Context())
+	if err != nil {
+		return false, err
+	}
+
+	filters := []db.ParticipantWhereParam{
+		db.Participant.ID.Equals(participant.ID),
+	}
+	if lastReadAt, ok := participant.LastReadAt(); ok {
+		if !message.CreatedAt.After(lastReadAt) {
+			return false, nil
+		}
+		// Checked again in the update, in case a newer receipt was stored
+		// in the meantime.
+		filters = append(filters, db.Participant.LastReadAt.Before(message.CreatedAt))
+	}
+
+	result, err := client.Participant.FindMany(filters...).Update(
+		db.Participant.LastReadAt.Set(message.CreatedAt),
+		db.Participant.LastReadMessageID.Set(message.ID),
+	).Exec(r.Context())
+	if err != nil {
+		return false, err
+	}
+
+	return result.Count > 0, nil
+}
+
+// streamRecipients leaves out the participants who blocked the sender, so
+// nothing of the sender reaches them in real time.
+func streamRecipients(r *http.Request, client *db.PrismaClient, senderID string, participantIDs []string) ([]string, error) {
+	blockerIDs, err := utils.BlockerIDs(r.Context(), client, senderID)
+	if err != nil {
+		return []string{senderID}, err
+	}
+
+	blockers := make(map[string]bool, len(blockerIDs))
+	for _, userID := range blockerIDs {
+		blockers[userID] = true
+	}
+
+	recipients := []string{}
+	for _, userID := range participantIDs {
+		if !blockers[userID] {
+			recipients = append(recipients, userID)
+		}
+	}
+
+	return recipients, nil
+}
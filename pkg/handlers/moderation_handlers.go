@@ -0,0 +1,345 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/models"
+	"vilow-be/pkg/search"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+const (
+	defaultReportLimit = 20
+	maxReportLimit     = 100
+)
+
+// GetReportsHandler lists reports oldest first, so the queue is worked in
+// the order content was flagged.
+func GetReportsHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		limit, err := queryLimit(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if limit == 0 {
+			limit = defaultReportLimit
+		}
+		if limit > maxReportLimit {
+			limit = maxReportLimit
+		}
+
+		status := r.URL.Query().Get("status")
+		if status == "" {
+			status = models.ReportOpen
+		}
+
+		filter := []db.ReportWhereParam{db.Report.Status.Equals(status)}
+		if targetType := r.URL.Query().Get("targetType"); targetType != "" {
+			filter = append(filter, db.Report.TargetType.Equals(targetType))
+		}
+
+		query := client.Report.FindMany(
+			filter...,
+		).With(
+			db.Report.Actions.Fetch(),
+		).OrderBy(
+			db.Report.CreatedAt.Order(db.ASC),
+		).Take(limit + 1)
+
+		if cursor := r.URL.Query().Get("cursor"); cursor != "" {
+			query = query.Cursor(db.Report.ID.Cursor(cursor)).Skip(1)
+		}
+
+		reports, err := query.Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching reports", http.StatusInternalServerError)
+			return
+		}
+
+		response := dto.ReportQueueResponse{Reports: []dto.Report{}}
+		if len(reports) > limit {
+			reports = reports[:limit]
+			response.NextCursor = reports[limit-1].ID
+		}
+
+		for i := range reports {
+			response.Reports = append(response.Reports, utils.BuildReportResponse(&reports[i]))
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting reports to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetReportHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		writeReport(w, r, client, mux.Vars(r)["id"])
+	}
+}
+
+// ModerateReportHandler applies a moderator's decision to the reported
+// content and resolves every open report on it. Each resolved report gets
+// its own audit entry.
+func ModerateReportHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string, index search.Index) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var decision models.ModerationDecision
+		if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		report, err := client.Report.FindUnique(
+			db.Report.ID.Equals(mux.Vars(r)["id"]),
+		).Exec(r.Context())
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "Report not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error fetching report", http.StatusInternalServerError)
+			return
+		}
+
+		if report.Status != models.ReportOpen {
+			apierror.Error(w, r, "Report was already resolved", http.StatusConflict)
+			return
+		}
+
+		status := models.ReportActioned
+		switch decision.Action {
+		case models.ModerationDismiss:
+			status = models.ReportDismissed
+		case models.ModerationHide:
+		case models.ModerationRemove:
+			if report.TargetType == models.ReportTargetUser {
+				apierror.Error(w, r, "Users can only be hidden", http.StatusBadRequest)
+				return
+			}
+		default:
+			apierror.Error(w, r, "action must be dismiss, hide or remove", http.StatusBadRequest)
+			return
+		}
+
+		ownerID, err := utils.ReportTargetOwner(r.Context(), client, report.TargetType, report.TargetID)
+		if errors.Is(err, db.ErrNotFound) && decision.Action != models.ModerationDismiss {
+			apierror.Error(w, r, "Reported content no longer exists", http.StatusGone)
+			return
+		} else if err != nil && !errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "Error fetching reported content", http.StatusInternalServerError)
+			return
+		}
+
+		openReports, err := client.Report.FindMany(
+			db.Report.TargetType.Equals(report.TargetType),
+			db.Report.TargetID.Equals(report.TargetID),
+			db.Report.Status.Equals(models.ReportOpen),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching reports", http.StatusInternalServerError)
+			return
+		}
+
+		now := time.Now()
+		var ops []transaction.Param
+		for _, openReport := range openReports {
+			ops = append(ops,
+				client.ModerationAction.CreateOne(
+					db.ModerationAction.Report.Link(
+						db.Report.ID.Equals(openReport.ID),
+					),
+					db.ModerationAction.Action.Set(decision.Action),
+					db.ModerationAction.Moderator.Link(
+						db.User.ID.Equals(authContext.UserID),
+					),
+					db.ModerationAction.Notes.Set(decision.Notes),
+				).Tx(),
+				client.Report.FindUnique(
+					db.Report.ID.Equals(openReport.ID),
+				).Update(
+					db.Report.Status.Set(status),
+					db.Report.ResolvedAt.Set(now),
+				).Tx(),
+			)
+		}
+
+		if err := applyModeration(r, client, minioClient, bucketName, index, report, decision.Action, ops); err != nil {
+			logging.FromContext(r.Context()).Error("Error applying moderation", "report_id", report.ID, "error", err)
+			apierror.Error(w, r, "Error applying moderation", http.StatusInternalServerError)
+			return
+		}
+
+		notifyModeration(r, client, report, openReports, ownerID, decision.Action)
+
+		writeReport(w, r, client, report.ID)
+	}
+}
+
+func UpdateUserRoleHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var update models.RoleUpdate
+		if err := json.NewDecoder(r.Body).Decode(&update); err != nil {
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if update.Role != models.RoleUser && update.Role != models.RoleModerator && update.Role != models.RoleAdmin {
+			apierror.Error(w, r, "role must be user, moderator or admin", http.StatusBadRequest)
+			return
+		}
+
+		updatedUser, err := client.User.FindUnique(
+			db.User.ID.Equals(mux.Vars(r)["id"]),
+		).Update(
+			db.User.Role.Set(update.Role),
+		).Exec(r.Context())
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error updating role", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(models.RoleUpdate{Role: updatedUser.Role})
+		if err != nil {
+			apierror.Error(w, r, "Error converting role to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+// applyModeration carries out action on the target of report in the same
+// transaction as resolve, the operations resolving its reports, so a report
+// is never closed without its action or the other way around.
+func applyModeration(r *http.Request, client *db.PrismaClient, minioClient *minio.Client, bucketName string, index search.Index, report *db.ReportModel, action string, resolve []transaction.Param) error {
+	switch action {
+	case models.ModerationHide:
+		ops := append([]transaction.Param{utils.HideReportTarget(client, report.TargetType, report.TargetID)}, resolve...)
+		if err := client.Prisma.Transaction(ops...).Exec(r.Context()); err != nil {
+			return err
+		}
+	case models.ModerationRemove:
+		if report.TargetType == models.ReportTargetComment {
+			ops := append([]transaction.Param{
+				client.Comment.FindUnique(
+					db.Comment.ID.Equals(report.TargetID),
+				).Delete().Tx(),
+			}, resolve...)
+			return client.Prisma.Transaction(ops...).Exec(r.Context())
+		}
+
+		media, err := client.Media.FindUnique(
+			db.Media.ID.Equals(report.TargetID),
+		).Exec(r.Context())
+		if err != nil {
+			return err
+		}
+
+		if err := utils.DeleteMedia(r.Context(), client, minioClient, bucketName, media, resolve...); err != nil {
+			return err
+		}
+	default:
+		return client.Prisma.Transaction(resolve...).Exec(r.Context())
+	}
+
+	// Hidden and removed content must not show up in search anymore.
+	var err error
+	switch report.TargetType {
+	case models.ReportTargetMedia:
+		err = index.Delete(search.KindMedia, report.TargetID)
+	case models.ReportTargetUser:
+		err = index.Delete(search.KindUser, report.TargetID)
+	}
+	if err != nil {
+		logging.FromContext(r.Context()).Error("Error removing report target from the search index", "target_type", report.TargetType, "target_id", report.TargetID, "error", err)
+	}
+
+	return nil
+}
+
+func notifyModeration(r *http.Request, client *db.PrismaClient, report *db.ReportModel, resolved []db.ReportModel, ownerID, action string) {
+	outcome, verb := "no action was taken", ""
+	switch action {
+	case models.ModerationHide:
+		outcome, verb = "the content was hidden", "hidden"
+	case models.ModerationRemove:
+		outcome, verb = "the content was removed", "removed"
+	}
+
+	notified := make(map[string]bool, len(resolved))
+	for _, resolvedReport := range resolved {
+		if notified[resolvedReport.ReporterID] {
+			continue
+		}
+		notified[resolvedReport.ReporterID] = true
+
+		content := fmt.Sprintf("Your report on a %s was reviewed: %s", reportTargetName(report.TargetType), outcome)
+		if err := utils.Notify(r.Context(), client, resolvedReport.ReporterID, content); err != nil {
+			logging.FromContext(r.Context()).Error("Error notifying user", "user_id", resolvedReport.ReporterID, "error", err)
+		}
+	}
+
+	if verb == "" || ownerID == "" {
+		return
+	}
+
+	content := fmt.Sprintf("Your %s was %s by moderators for %s", reportTargetName(report.TargetType), verb, report.Reason)
+	if err := utils.Notify(r.Context(), client, ownerID, content); err != nil {
+		logging.FromContext(r.Context()).Error("Error notifying user", "user_id", ownerID, "error", err)
+	}
+}
+
+func reportTargetName(targetType string) string {
+	switch targetType {
+	case models.ReportTargetMedia:
+		return "video"
+	case models.ReportTargetUser:
+		return "profile"
+	default:
+		return targetType
+	}
+}
+
+func writeReport(w http.ResponseWriter, r *http.Request, client *db.PrismaClient, id string) {
+	report, err := client.Report.FindUnique(
+		db.Report.ID.Equals(id),
+	).With(
+		db.Report.Actions.Fetch().OrderBy(
+			db.ModerationAction.CreatedAt.Order(db.ASC),
+		),
+	).Exec(r.Context())
+	if errors.Is(err, db.ErrNotFound) {
+		apierror.Error(w, r, "Report not found", http.StatusNotFound)
+		return
+	} else if err != nil {
+		apierror.Error(w, r, "Error fetching report", http.StatusInternalServerError)
+		return
+	}
+
+	err = json.NewEncoder(w).Encode(utils.BuildReportResponse(report))
+	if err != nil {
+		apierror.Error(w, r, "Error converting report to JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,475 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/models"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+func CreatePlaylistHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var playlist models.PlaylistInput
+		err := json.NewDecoder(r.Body).Decode(&playlist)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if playlist.Title == "" || !utils.ValidatePlaylist(&playlist) {
+			apierror.Error(w, r, "Invalid playlist data", http.StatusBadRequest)
+			return
+		}
+
+		if playlist.Visibility == "" {
+			playlist.Visibility = models.PlaylistPublic
+		}
+
+		createdPlaylist, err := client.Playlist.CreateOne(
+			db.Playlist.Title.Set(playlist.Title),
+			db.Playlist.Description.Set(playlist.Description),
+			db.Playlist.User.Link(
+				db.User.ID.Equals(authContext.UserID),
+			),
+			db.Playlist.Visibility.Set(playlist.Visibility),
+		).Exec(r.Context())
+
+		if err != nil {
+			apierror.Error(w, r, "Error creating playlist in the database", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(utils.BuildPlaylistResponse(createdPlaylist))
+		if err != nil {
+			apierror.Error(w, r, "Error converting playlist to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetPlaylistsHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		// Make sure the system playlist shows up even before anything was saved to it.
+		_, err := utils.GetOrCreateWatchLater(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching playlists", http.StatusInternalServerError)
+			return
+		}
+
+		playlists, err := client.Playlist.FindMany(
+			db.Playlist.UserID.Equals(authContext.UserID),
+		).With(
+			utils.PlaylistItemsFetch(),
+		).OrderBy(
+			db.Playlist.CreatedAt.Order(db.ASC),
+		).Exec(r.Context())
+
+		if err != nil {
+			apierror.Error(w, r, "Error fetching playlists", http.StatusInternalServerError)
+			return
+		}
+
+		filtered := make([]*db.PlaylistModel, len(playlists))
+		for i := range playlists {
+			filtered[i] = &playlists[i]
+		}
+		if err := utils.FilterPlaylistItems(r.Context(), client, authContext.UserID, filtered...); err != nil {
+			apierror.Error(w, r, "Error fetching playlists", http.StatusInternalServerError)
+			return
+		}
+
+		response := make([]dto.Playlist, len(playlists))
+		for i := range playlists {
+			response[i] = utils.BuildPlaylistResponse(&playlists[i])
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting playlists to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetWatchLaterHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		playlist, err := utils.GetOrCreateWatchLater(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching watch later playlist", http.StatusInternalServerError)
+			return
+		}
+
+		if err := utils.FilterPlaylistItems(r.Context(), client, authContext.UserID, playlist); err != nil {
+			apierror.Error(w, r, "Error fetching watch later playlist", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(utils.BuildPlaylistResponse(playlist))
+		if err != nil {
+			apierror.Error(w, r, "Error converting playlist to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func GetPlaylistHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		playlistID := mux.Vars(r)["id"]
+
+		playlist, err := client.Playlist.FindUnique(
+			db.Playlist.ID.Equals(playlistID),
+		).With(
+			utils.PlaylistItemsFetch(),
+		).Exec(r.Context())
+
+		if err != nil || !utils.CanViewPlaylist(playlist, authContext.UserID) {
+			apierror.Error(w, r, "Playlist not found", http.StatusNotFound)
+			return
+		}
+
+		if err := utils.FilterPlaylistItems(r.Context(), client, authContext.UserID, playlist); err != nil {
+			apierror.Error(w, r, "Error fetching playlist", http.StatusInternalServerError)
+			return
+		}
+
+		err = json.NewEncoder(w).Encode(utils.BuildPlaylistResponse(playlist))
+		if err != nil {
+			apierror.Error(w, r, "Error converting playlist to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func UpdatePlaylistHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playlistID := mux.Vars(r)["id"]
+
+		playlist, errStatusCode, err := getPlaylistForOwner(r, client, playlistID)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		if playlist.Kind != models.PlaylistKindCustom {
+			apierror.Error(w, r, "System playlists cannot be edited", http.StatusForbidden)
+			return
+		}
+
+		var update models.PlaylistInput
+		err = json.NewDecoder(r.Body).Decode(&update)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if !utils.ValidatePlaylist(&update) {
+			apierror.Error(w, r, "Invalid playlist data", http.StatusBadRequest)
+			return
+		}
+
+		var updateData []db.PlaylistSetParam
+		if update.Title != "" {
+			updateData = append(updateData, db.Playlist.Title.Set(update.Title))
+		}
+		if update.Description != "" {
+			updateData = append(updateData, db.Playlist.Description.Set(update.Description))
+		}
+		if update.Visibility != "" {
+			updateData = append(updateData, db.Playlist.Visibility.Set(update.Visibility))
+		}
+
+		if len(updateData) == 0 {
+			apierror.Error(w, r, "No data to update", http.StatusBadRequest)
+			return
+		}
+
+		_, err = client.Playlist.FindUnique(
+			db.Playlist.ID.Equals(playlistID),
+		).Update(
+			updateData...,
+		).Exec(r.Context())
+
+		if err != nil {
+			apierror.Error(w, r, "Error updating playlist in the database", http.StatusInternalServerError)
+			return
+		}
+
+		writePlaylist(w, r, client, playlistID, http.StatusOK)
+	}
+}
+
+func DeletePlaylistHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playlistID := mux.Vars(r)["id"]
+
+		playlist, errStatusCode, err := getPlaylistForOwner(r, client, playlistID)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		if playlist.Kind != models.PlaylistKindCustom {
+			apierror.Error(w, r, "System playlists cannot be deleted", http.StatusForbidden)
+			return
+		}
+
+		_, err = client.PlaylistItem.FindMany(
+			db.PlaylistItem.PlaylistID.Equals(playlistID),
+		).Delete().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error deleting playlist items", http.StatusInternalServerError)
+			return
+		}
+
+		_, err = client.Playlist.FindUnique(
+			db.Playlist.ID.Equals(playlistID),
+		).Delete().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error deleting playlist from database", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func AddPlaylistItemHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playlistID := mux.Vars(r)["id"]
+
+		playlist, errStatusCode, err := getPlaylistForOwner(r, client, playlistID)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		var item models.PlaylistItemInput
+		err = json.NewDecoder(r.Body).Decode(&item)
+		if err != nil || item.MediaID == "" {
+			apierror.Error(w, r, "Invalid playlist item data", http.StatusBadRequest)
+			return
+		}
+
+		// Only media the owner may watch can be added, the playlist could be
+		// public.
+		_, _, errStatusCode, err = getVisibleMedia(r, client, item.MediaID)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		for _, existing := range playlist.Items() {
+			if existing.MediaID == item.MediaID {
+				apierror.Error(w, r, "Media already in playlist", http.StatusConflict)
+				return
+			}
+		}
+
+		// Items are fetched in position order, so the new item goes after the last one.
+		position := 0
+		if items := playlist.Items(); len(items) > 0 {
+			position = items[len(items)-1].Position + 1
+		}
+
+		_, err = client.PlaylistItem.CreateOne(
+			db.PlaylistItem.Playlist.Link(
+				db.Playlist.ID.Equals(playlistID),
+			),
+			db.PlaylistItem.Media.Link(
+				db.Media.ID.Equals(item.MediaID),
+			),
+			db.PlaylistItem.Position.Set(position),
+		).Exec(r.Context())
+
+		if err != nil {
+			apierror.Error(w, r, "Error adding media to playlist", http.StatusInternalServerError)
+			return
+		}
+
+		writePlaylist(w, r, client, playlistID, http.StatusCreated)
+	}
+}
+
+func RemovePlaylistItemHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		playlistID := vars["id"]
+		mediaID := vars["mediaId"]
+
+		playlist, errStatusCode, err := getPlaylistForOwner(r, client, playlistID)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		var removed *db.PlaylistItemModel
+		for i, item := range playlist.Items() {
+			if item.MediaID == mediaID {
+				removed = &playlist.Items()[i]
+				break
+			}
+		}
+
+		if removed == nil {
+			apierror.Error(w, r, "Media not in playlist", http.StatusNotFound)
+			return
+		}
+
+		_, err = client.PlaylistItem.FindUnique(
+			db.PlaylistItem.ID.Equals(removed.ID),
+		).Delete().Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error removing media from playlist", http.StatusInternalServerError)
+			return
+		}
+
+		// Close the gap left behind so positions stay contiguous.
+		_, err = client.PlaylistItem.FindMany(
+			db.PlaylistItem.PlaylistID.Equals(playlistID),
+			db.PlaylistItem.Position.Gt(removed.Position),
+		).Update(
+			db.PlaylistItem.Position.Decrement(1),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error reordering playlist items", http.StatusInternalServerError)
+			return
+		}
+
+		writePlaylist(w, r, client, playlistID, http.StatusOK)
+	}
+}
+
+func ReorderPlaylistHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		playlistID := mux.Vars(r)["id"]
+
+		playlist, errStatusCode, err := getPlaylistForOwner(r, client, playlistID)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		var order models.PlaylistOrder
+		err = json.NewDecoder(r.Body).Decode(&order)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		itemsByMedia := make(map[string]string, len(playlist.Items()))
+		for _, item := range playlist.Items() {
+			itemsByMedia[item.MediaID] = item.ID
+		}
+
+		if len(order.MediaIDs) != len(itemsByMedia) {
+			apierror.Error(w, r, "Order must list every media in the playlist exactly once", http.StatusBadRequest)
+			return
+		}
+
+		updates := make([]transaction.Param, 0, len(order.MediaIDs))
+		for position, mediaID := range order.MediaIDs {
+			itemID, ok := itemsByMedia[mediaID]
+			if !ok {
+				apierror.Error(w, r, "Order must list every media in the playlist exactly once", http.StatusBadRequest)
+				return
+			}
+			delete(itemsByMedia, mediaID)
+
+			updates = append(updates, client.PlaylistItem.FindUnique(
+				db.PlaylistItem.ID.Equals(itemID),
+			).Update(
+				db.PlaylistItem.Position.Set(position),
+			).Tx())
+		}
+
+		err = client.Prisma.Transaction(updates...).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error reordering playlist items", http.StatusInternalServerError)
+			return
+		}
+
+		writePlaylist(w, r, client, playlistID, http.StatusOK)
+	}
+}
+
+func getPlaylistForOwner(r *http.Request, client *db.PrismaClient, playlistID string) (*db.PlaylistModel, int, error) {
+	authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+	if !ok {
+		return nil, http.StatusInternalServerError, errors.New("AuthContext not found in context")
+	}
+
+	playlist, err := client.Playlist.FindUnique(
+		db.Playlist.ID.Equals(playlistID),
+	).With(
+		utils.PlaylistItemsFetch(),
+	).Exec(r.Context())
+
+	if err != nil || !utils.CanViewPlaylist(playlist, authContext.UserID) {
+		return nil, http.StatusNotFound, errors.New("playlist not found")
+	}
+
+	if playlist.UserID != authContext.UserID {
+		return nil, http.StatusForbidden, errors.New("forbidden: You do not have permission to manipulate this playlist")
+	}
+
+	return playlist, http.StatusOK, nil
+}
+
+func writePlaylist(w http.ResponseWriter, r *http.Request, client *db.PrismaClient, playlistID string, status int) {
+	playlist, err := client.Playlist.FindUnique(
+		db.Playlist.ID.Equals(playlistID),
+	).With(
+		utils.PlaylistItemsFetch(),
+	).Exec(r.Context())
+
+	if err != nil {
+		apierror.Error(w, r, "Error fetching playlist", http.StatusInternalServerError)
+		return
+	}
+
+	// Only the owner gets here, after changing the playlist.
+	if err := utils.FilterPlaylistItems(r.Context(), client, playlist.UserID, playlist); err != nil {
+		apierror.Error(w, r, "Error fetching playlist", http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(status)
+	err = json.NewEncoder(w).Encode(utils.BuildPlaylistResponse(playlist))
+	if err != nil {
+		apierror.Error(w, r, "Error converting playlist to JSON", http.StatusInternalServerError)
+		return
+	}
+}
@@ -0,0 +1,120 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/metrics"
+	"vilow-be/pkg/models"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+
+	"github.com/gorilla/mux"
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+// ReactHandler sets the user's reaction on a media. Liking replaces a
+// dislike and the other way around.
+func ReactHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, media, errStatusCode, err := getInteractableMedia(r, client, mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		var reaction models.Reaction
+		if err := json.NewDecoder(r.Body).Decode(&reaction); err != nil {
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := utils.ValidateReaction(&reaction); err != nil {
+			apierror.Write(w, r, err)
+			return
+		}
+
+		ops := clearReaction(client, authContext.UserID, media.ID)
+		if reaction.Type == models.ReactionLike {
+			ops = append(ops, client.Like.CreateOne(
+				db.Like.User.Link(db.User.ID.Equals(authContext.UserID)),
+				db.Like.Media.Link(db.Media.ID.Equals(media.ID)),
+			).Tx())
+		} else {
+			ops = append(ops, client.Dislike.CreateOne(
+				db.Dislike.User.Link(db.User.ID.Equals(authContext.UserID)),
+				db.Dislike.Media.Link(db.Media.ID.Equals(media.ID)),
+			).Tx())
+		}
+
+		if err := client.Prisma.Transaction(ops...).Exec(r.Context()); err != nil {
+			apierror.Error(w, r, "Error saving reaction", http.StatusInternalServerError)
+			return
+		}
+
+		metrics.Reactions.WithLabelValues(reaction.Type).Inc()
+
+		writeReaction(w, r, client, media.ID, reaction.Type)
+	}
+}
+
+func DeleteReactionHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, media, errStatusCode, err := getInteractableMedia(r, client, mux.Vars(r)["id"])
+		if err != nil {
+			apierror.Error(w, r, err.Error(), errStatusCode)
+			return
+		}
+
+		if err := client.Prisma.Transaction(clearReaction(client, authContext.UserID, media.ID)...).Exec(r.Context()); err != nil {
+			apierror.Error(w, r, "Error deleting reaction", http.StatusInternalServerError)
+			return
+		}
+
+		writeReaction(w, r, client, media.ID, "")
+	}
+}
+
+func clearReaction(client *db.PrismaClient, userID, mediaID string) []transaction.Param {
+	return []transaction.Param{
+		client.Like.FindMany(
+			db.Like.UserID.Equals(userID),
+			db.Like.MediaID.Equals(mediaID),
+		).Delete().Tx(),
+		client.Dislike.FindMany(
+			db.Dislike.UserID.Equals(userID),
+			db.Dislike.MediaID.Equals(mediaID),
+		).Delete().Tx(),
+	}
+}
+
+func writeReaction(w http.ResponseWriter, r *http.Request, client *db.PrismaClient, mediaID, reaction string) {
+	likes, err := client.Like.FindMany(
+		db.Like.MediaID.Equals(mediaID),
+	).Exec(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Error fetching likes", http.StatusInternalServerError)
+		return
+	}
+
+	dislikes, err := client.Dislike.FindMany(
+		db.Dislike.MediaID.Equals(mediaID),
+	).Exec(r.Context())
+	if err != nil {
+		apierror.Error(w, r, "Error fetching dislikes", http.StatusInternalServerError)
+		return
+	}
+
+	response := dto.ReactionResponse{
+		Reaction: reaction,
+		Likes:    len(likes),
+		Dislikes: len(dislikes),
+	}
+
+	err = json.NewEncoder(w).Encode(response)
+	if err != nil {
+		apierror.Error(w, r, "Error converting reaction to JSON", http.StatusInternalServerError)
+		return
+	}
+}
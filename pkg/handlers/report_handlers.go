@@ -0,0 +1,84 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/models"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+)
+
+func CreateReportHandler(client *db.PrismaClient) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		var report models.Report
+		if err := json.NewDecoder(r.Body).Decode(&report); err != nil {
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := utils.ValidateReport(&report); err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		ownerID, err := utils.ReportTargetOwner(r.Context(), client, report.TargetType, report.TargetID)
+		if errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "Reported content not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error fetching reported content", http.StatusInternalServerError)
+			return
+		}
+
+		if ownerID == authContext.UserID {
+			apierror.Error(w, r, "You cannot report your own content", http.StatusBadRequest)
+			return
+		}
+
+		_, err = client.Report.FindFirst(
+			db.Report.ReporterID.Equals(authContext.UserID),
+			db.Report.TargetType.Equals(report.TargetType),
+			db.Report.TargetID.Equals(report.TargetID),
+			db.Report.Status.Equals(models.ReportOpen),
+		).Exec(r.Context())
+
+		if err == nil {
+			apierror.Error(w, r, "You already reported this content", http.StatusConflict)
+			return
+		} else if !errors.Is(err, db.ErrNotFound) {
+			apierror.Error(w, r, "Error fetching reports", http.StatusInternalServerError)
+			return
+		}
+
+		createdReport, err := client.Report.CreateOne(
+			db.Report.Reporter.Link(
+				db.User.ID.Equals(authContext.UserID),
+			),
+			db.Report.TargetType.Set(report.TargetType),
+			db.Report.TargetID.Set(report.TargetID),
+			db.Report.Reason.Set(report.Reason),
+			db.Report.Notes.Set(report.Notes),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error creating report", http.StatusInternalServerError)
+			return
+		}
+
+		w.WriteHeader(http.StatusCreated)
+		err = json.NewEncoder(w).Encode(utils.BuildReportResponse(createdReport))
+		if err != nil {
+			apierror.Error(w, r, "Error converting report to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/middleware"
+	"vilow-be/pkg/search"
+	"vilow-be/pkg/utils"
+	"vilow-be/prisma/db"
+)
+
+func SearchHandler(client *db.PrismaClient, index search.Index) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		if !ok {
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			return
+		}
+
+		query, err := parseSearchQuery(r)
+		if err != nil {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		page, err := index.Search(query)
+		if errors.Is(err, search.ErrInvalidCursor) {
+			apierror.Error(w, r, err.Error(), http.StatusBadRequest)
+			return
+		} else if err != nil {
+			apierror.Error(w, r, "Error searching", http.StatusInternalServerError)
+			return
+		}
+
+		blockerIDs, err := utils.BlockerIDs(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+			return
+		}
+
+		visible, err := utils.VisibleMediaFilter(r.Context(), client, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching follows", http.StatusInternalServerError)
+			return
+		}
+
+		var mediaIDs, userIDs []string
+		for _, hit := range page.Hits {
+			if hit.Kind == search.KindMedia {
+				mediaIDs = append(mediaIDs, hit.ID)
+			} else {
+				userIDs = append(userIDs, hit.ID)
+			}
+		}
+
+		medias, err := client.Media.FindMany(
+			db.Media.ID.In(mediaIDs),
+			db.Media.Hidden.Equals(false),
+			db.Media.UserID.NotIn(blockerIDs),
+			visible,
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching medias", http.StatusInternalServerError)
+			return
+		}
+
+		users, err := client.User.FindMany(
+			db.User.ID.In(userIDs),
+			db.User.Hidden.Equals(false),
+			db.User.ID.NotIn(blockerIDs),
+		).Exec(r.Context())
+		if err != nil {
+			apierror.Error(w, r, "Error fetching users", http.StatusInternalServerError)
+			return
+		}
+
+		mediasByID := make(map[string]*db.MediaModel, len(medias))
+		for i := range medias {
+			mediasByID[medias[i].ID] = &medias[i]
+		}
+
+		bookmarked, err := utils.BookmarkedMediaIDs(r.Context(), client, authContext.UserID, mediaIDs)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching bookmarks", http.StatusInternalServerError)
+			return
+		}
+
+		usersByID := make(map[string]*db.UserModel, len(users))
+		for i := range users {
+			usersByID[users[i].ID] = &users[i]
+		}
+
+		// Keep the index ordering and drop hits whose record is already gone.
+		response := dto.SearchResponse{
+			Results:    []dto.SearchResult{},
+			NextCursor: page.NextCursor,
+		}
+
+		for _, hit := range page.Hits {
+			result := dto.SearchResult{Type: hit.Kind, Score: hit.Score}
+
+			if media, ok := mediasByID[hit.ID]; ok && hit.Kind == search.KindMedia {
+				mediaResponse := utils.BuildMediaResponse(media)
+				mediaResponse.IsBookmarked = bookmarked[media.ID]
+				result.Media = &mediaResponse
+			} else if user, ok := usersByID[hit.ID]; ok && hit.Kind == search.KindUser {
+				result.User = &dto.User{
+					ID:          user.ID,
+					Name:        user.Name,
+					StrID:       user.StrID,
+					Description: user.Description,
+					Subjects:    user.Subjects,
+				}
+			} else {
+				continue
+			}
+
+			response.Results = append(response.Results, result)
+		}
+
+		err = json.NewEncoder(w).Encode(response)
+		if err != nil {
+			apierror.Error(w, r, "Error converting search results to JSON", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func parseSearchQuery(r *http.Request) (search.Query, error) {
+	values := r.URL.Query()
+
+	query := search.Query{
+		Text:    values.Get("q"),
+		Kind:    values.Get("type"),
+		Subject: values.Get("subject"),
+		OwnerID: values.Get("uploader"),
+		Cursor:  values.Get("cursor"),
+	}
+
+	if query.Kind != "" && query.Kind != search.KindMedia && query.Kind != search.KindUser {
+		return query, errors.New("type must be media or user")
+	}
+
+	var err error
+	if query.Limit, err = queryLimit(r); err != nil {
+		return query, err
+	}
+	if query.From, err = parseSearchDate(values.Get("from"), false); err != nil {
+		return query, errors.New("from must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	}
+	if query.To, err = parseSearchDate(values.Get("to"), true); err != nil {
+		return query, errors.New("to must be a date (YYYY-MM-DD) or RFC 3339 timestamp")
+	}
+
+	return query, nil
+}
+
+// parseSearchDate accepts full timestamps or plain dates. A plain date used
+// as an upper bound covers that whole day.
+func parseSearchDate(value string, endOfDay bool) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+
+	t, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return t, nil
+}
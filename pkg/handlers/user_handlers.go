@@ -3,30 +3,43 @@ package handlers
 import (
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
+	"vilow-be/pkg/apierror"
 	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/pkg/metrics"
 	"vilow-be/pkg/middleware"
 	"vilow-be/pkg/models"
+	"vilow-be/pkg/search"
+	"vilow-be/pkg/timeline"
 	"vilow-be/pkg/utils"
 	"vilow-be/prisma/db"
 
 	"github.com/gorilla/mux"
+	"github.com/minio/minio-go/v7"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func CreateUserHandler(client *db.PrismaClient) http.HandlerFunc {
+func CreateUserHandler(client *db.PrismaClient, index search.Index) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		var user models.User
+		logger := logging.FromContext(r.Context())
+
+		var user models.Signup
 		err := json.NewDecoder(r.Body).Decode(&user)
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			return
+		}
+
+		if err := utils.ValidateNewUser(&user); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error generating password hash: %v", err), http.StatusInternalServerError)
+			logger.Error("Error generating password hash", "error", err)
+			apierror.Error(w, r, "Error generating password hash", http.StatusInternalServerError)
 			return
 		}
 
@@ -35,7 +48,7 @@ func CreateUserHandler(client *db.PrismaClient) http.HandlerFunc {
 		).Exec(r.Context())
 
 		if err == nil && existingUser != nil {
-			http.Error(w, "E-mail already in use", http.StatusConflict)
+			apierror.Error(w, r, "E-mail already in use", http.StatusConflict)
 			return
 		}
 
@@ -48,34 +61,42 @@ func CreateUserHandler(client *db.PrismaClient) http.HandlerFunc {
 		).Exec(r.Context())
 
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error creating a new user: %v", err), http.StatusInternalServerError)
+			logger.Error("Error creating user", "error", err)
+			apierror.Error(w, r, "Error creating a new user", http.StatusInternalServerError)
 			return
 		}
 
+		metrics.Signups.Inc()
+
+		if err := index.Upsert(utils.UserDocument(createdUser)); err != nil {
+			logger.Error("Error indexing user", "user_id", createdUser.ID, "error", err)
+		}
+
 		fmt.Fprintf(w, "User created! ID: %s", createdUser.ID)
 	}
 }
 
-func UpdateUserHandler(client *db.PrismaClient) http.HandlerFunc {
+func UpdateUserHandler(client *db.PrismaClient, index search.Index, timelines *timeline.Service) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.FromContext(r.Context())
+
 		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
 		if !ok {
-			http.Error(w, "AuthContext not found in context", http.StatusInternalServerError)
-			log.Println("AuthContext not found in context")
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			logger.Error("AuthContext not found in context")
 			return
 		}
 
-		var user models.User
+		var user models.UserUpdate
 		err := json.NewDecoder(r.Body).Decode(&user)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error decoding request body: %v", err), http.StatusBadRequest)
-			log.Printf("Error decoding request body: %v\n", err)
+			apierror.Error(w, r, "Invalid request body", http.StatusBadRequest)
+			logger.Warn("Error decoding request body", "error", err)
 			return
 		}
 
-		if !utils.ValidateUser(&user) {
-			http.Error(w, "Invalid user data", http.StatusBadRequest)
-			log.Println("Invalid user data")
+		if err := utils.ValidateUserUpdate(&user); err != nil {
+			apierror.Write(w, r, err)
 			return
 		}
 
@@ -84,44 +105,67 @@ func UpdateUserHandler(client *db.PrismaClient) http.HandlerFunc {
 		).Exec(r.Context())
 
 		if err != nil || existingUser == nil {
-			http.Error(w, "User not found: "+err.Error(), http.StatusNotFound)
-			log.Println("User not found: " + err.Error())
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			logger.Warn("User not found", "error", err)
 			return
 		}
 
 		updateData, err := utils.BuildUpdateData(&user)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error building update data: %v", err), http.StatusInternalServerError)
-			log.Printf("Error building update data: %v\n", err)
+			apierror.Error(w, r, "Error building update data", http.StatusInternalServerError)
+			logger.Error("Error building update data", "error", err)
 			return
 		} else if len(updateData) == 0 {
-			http.Error(w, "No data to update", http.StatusBadRequest)
-			log.Println("No data to update")
+			apierror.Error(w, r, "No data to update", http.StatusBadRequest)
+			logger.Warn("No data to update")
 			return
 		}
 
-		_, err = client.User.FindUnique(
+		updatedUser, err := client.User.FindUnique(
 			db.User.ID.Equals(existingUser.ID),
 		).Update(
 			updateData...,
 		).Exec(r.Context())
 
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error updating user: %v", err), http.StatusInternalServerError)
-			log.Printf("Error updating user: %v\n", err)
+			apierror.Error(w, r, "Error updating user", http.StatusInternalServerError)
+			logger.Error("Error updating user", "error", err)
 			return
 		}
 
+		if err := utils.IndexUser(index, updatedUser); err != nil {
+			logger.Error("Error indexing user", "user_id", updatedUser.ID, "error", err)
+		}
+
+		// Going public makes every pending follow request moot, so they are
+		// approved on the user's behalf.
+		if existingUser.IsPrivate && !updatedUser.IsPrivate {
+			requests, err := client.FollowRequest.FindMany(
+				db.FollowRequest.TargetID.Equals(updatedUser.ID),
+			).Exec(r.Context())
+			if err != nil {
+				logger.Error("Error fetching follow requests", "user_id", updatedUser.ID, "error", err)
+			}
+
+			for i := range requests {
+				if err := approveFollowRequest(r.Context(), client, timelines, &requests[i], updatedUser.StrID); err != nil {
+					logger.Error("Error approving follow request", "follow_request_id", requests[i].ID, "error", err)
+				}
+			}
+		}
+
 		fmt.Fprintf(w, "User updated! ID: %s", existingUser.ID)
 	}
 }
 
-func DeleteUserHandler(client *db.PrismaClient) http.HandlerFunc {
+func DeleteUserHandler(client *db.PrismaClient, minioClient *minio.Client, bucketName string, index search.Index) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		logger := logging.FromContext(r.Context())
+
 		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
 		if !ok {
-			http.Error(w, "AuthContext not found in context", http.StatusInternalServerError)
-			log.Println("AuthContext not found in context")
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			logger.Error("AuthContext not found in context")
 			return
 		}
 
@@ -130,54 +174,123 @@ func DeleteUserHandler(client *db.PrismaClient) http.HandlerFunc {
 		).Exec(r.Context())
 
 		if err != nil || existingUser == nil {
-			http.Error(w, "User not found: "+err.Error(), http.StatusNotFound)
-			log.Println("User not found: " + err.Error())
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			logger.Warn("User not found", "error", err)
 			return
 		}
 
-		_, err = client.User.FindUnique(
-			db.User.ID.Equals(existingUser.ID),
-		).Delete().Exec(r.Context())
-
+		err = utils.DeleteUser(r.Context(), client, minioClient, bucketName, index, existingUser)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error deleting user: %v", err), http.StatusInternalServerError)
-			log.Printf("Error deleting user: %v\n", err)
+			apierror.Error(w, r, "Error deleting user", http.StatusInternalServerError)
+			logger.Error("Error deleting user", "error", err)
 			return
 		}
 
+		if err := index.Delete(search.KindUser, existingUser.ID); err != nil {
+			logger.Error("Error removing user from the search index", "user_id", existingUser.ID, "error", err)
+		}
+
 		fmt.Fprintf(w, "User deleted! ID: %s", existingUser.ID)
 	}
 }
 
 func GetUserDataHandler(client *db.PrismaClient) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		_, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
+		logger := logging.FromContext(r.Context())
+
+		authContext, ok := r.Context().Value(middleware.AuthContextKey("authContext")).(dto.AuthContext)
 		if !ok {
-			http.Error(w, "AuthContext not found in context", http.StatusInternalServerError)
-			log.Println("AuthContext not found in context")
+			apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+			logger.Error("AuthContext not found in context")
 			return
 		}
 
 		vars := mux.Vars(r)
 		id := vars["id"]
 
+		// Hidden media are left out unless the profile is the visitor's own.
+		mediaFilter := []db.MediaWhereParam{}
+		if id != authContext.StrID {
+			mediaFilter = append(mediaFilter, db.Media.Hidden.Equals(false))
+		}
+
 		existingUser, err := client.User.FindUnique(
 			db.User.StrID.Equals(id),
+		).With(
+			db.User.Medias.Fetch(mediaFilter...).With(
+				db.Media.Likes.Fetch(),
+				db.Media.Dislikes.Fetch(),
+				db.Media.Comments.Fetch(
+					db.Comment.Hidden.Equals(false),
+				),
+			),
+			// Visitors only get to see public playlists on someone else's profile.
+			db.User.Playlists.Fetch(
+				db.Playlist.Or(
+					db.Playlist.Visibility.Equals(models.PlaylistPublic),
+					db.Playlist.UserID.Equals(authContext.UserID),
+				),
+			).With(
+				utils.PlaylistItemsFetch(),
+			),
 		).Exec(r.Context())
 
 		if err != nil || existingUser == nil {
-			http.Error(w, "User not found: "+err.Error(), http.StatusNotFound)
-			log.Println("User not found: " + err.Error())
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			logger.Warn("User not found", "error", err)
+			return
+		}
+
+		if existingUser.Hidden && existingUser.ID != authContext.UserID && !utils.IsModerator(authContext.Role) {
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			return
+		}
+
+		blocked, err := utils.HasBlocked(r.Context(), client, existingUser.ID, authContext.UserID)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching blocks", http.StatusInternalServerError)
+			return
+		} else if blocked {
+			apierror.Error(w, r, "User not found", http.StatusNotFound)
+			return
+		}
+
+		// Private profiles only expose their media to approved followers.
+		allowed, err := utils.CanViewContent(r.Context(), client, authContext.UserID, existingUser)
+		if err != nil {
+			apierror.Error(w, r, "Error fetching follows", http.StatusInternalServerError)
+			return
+		} else if !allowed {
+			existingUser.RelationsUser.Medias = nil
+			existingUser.RelationsUser.Playlists = nil
+		}
+
+		playlists := make([]*db.PlaylistModel, len(existingUser.RelationsUser.Playlists))
+		for i := range existingUser.RelationsUser.Playlists {
+			playlists[i] = &existingUser.RelationsUser.Playlists[i]
+		}
+		if err := utils.FilterPlaylistItems(r.Context(), client, authContext.UserID, playlists...); err != nil {
+			apierror.Error(w, r, "Error fetching playlists", http.StatusInternalServerError)
 			return
 		}
 
 		response, err := utils.BuildResponse(existingUser)
 		if err != nil {
-			http.Error(w, fmt.Sprintf("Error building response: %v", err), http.StatusInternalServerError)
-			log.Printf("Error building response: %v\n", err)
+			apierror.Error(w, r, "Error building response", http.StatusInternalServerError)
+			logger.Error("Error building response", "error", err)
+			return
+		}
+
+		// E-mail addresses are only shown to their owner.
+		if existingUser.ID != authContext.UserID {
+			response.Email = ""
+		}
+
+		if err := utils.MarkBookmarked(r.Context(), client, authContext.UserID, response.Medias); err != nil {
+			apierror.Error(w, r, "Error fetching bookmarks", http.StatusInternalServerError)
 			return
 		}
 
-		utils.SendResponse(w, response)
+		utils.SendResponse(w, r, response)
 	}
 }
@@ -0,0 +1,154 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK       = "ok"
+	StatusFailing  = "failing"
+	StatusDegraded = "degraded"
+
+	// DefaultTimeout bounds every check, a dependency slower than that is
+	// as good as down for a probe.
+	DefaultTimeout = 2 * time.Second
+)
+
+var (
+	ErrShuttingDown  = errors.New("server is shutting down")
+	ErrWorkerStopped = errors.New("worker stopped")
+)
+
+// Check probes one dependency and returns an error when it is unusable.
+type Check func(ctx context.Context) error
+
+// Result is the outcome of one check.
+type Result struct {
+	Name    string
+	Err     error
+	Latency time.Duration
+}
+
+// Report is the outcome of every check. Status is StatusOK when all of them
+// passed, StatusDegraded while shutting down and StatusFailing otherwise.
+type Report struct {
+	Status  string
+	Results []Result
+}
+
+// Ready reports whether the server should receive traffic.
+func (r Report) Ready() bool {
+	return r.Status == StatusOK
+}
+
+// Checker runs the readiness checks of the server.
+type Checker struct {
+	timeout      time.Duration
+	mu           sync.RWMutex
+	checks       map[string]Check
+	shuttingDown atomic.Bool
+}
+
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{
+		timeout: timeout,
+		checks:  make(map[string]Check),
+	}
+}
+
+// Register adds a check under name, replacing any check of that name.
+func (c *Checker) Register(name string, check Check) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.checks[name] = check
+}
+
+// Worker registers a check for a background worker named name. The check
+// passes until the returned function is called when the worker returns.
+func (c *Checker) Worker(name string) func() {
+	var stopped atomic.Bool
+	c.Register(name, func(ctx context.Context) error {
+		if stopped.Load() {
+			return ErrWorkerStopped
+		}
+		return nil
+	})
+
+	return func() {
+		stopped.Store(true)
+	}
+}
+
+// SetShuttingDown makes every later report degraded, so load balancers stop
+// routing to the server while it drains.
+func (c *Checker) SetShuttingDown() {
+	c.shuttingDown.Store(true)
+}
+
+// Run runs every check concurrently and reports them sorted by name.
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	names := make([]string, 0, len(c.checks))
+	for name := range c.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	checks := make([]Check, len(names))
+	for i, name := range names {
+		checks[i] = c.checks[name]
+	}
+	c.mu.RUnlock()
+
+	results := make([]Result, len(names))
+	var wg sync.WaitGroup
+	for i := range names {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = c.run(ctx, names[i], checks[i])
+		}(i)
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Results: results}
+	for _, result := range results {
+		if result.Err != nil {
+			report.Status = StatusFailing
+		}
+	}
+
+	if c.shuttingDown.Load() {
+		report.Status = StatusDegraded
+		report.Results = append(report.Results, Result{Name: "shutdown", Err: ErrShuttingDown})
+	}
+
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, name string, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errs := make(chan error, 1)
+	go func() {
+		errs <- check(ctx)
+	}()
+
+	// A check that ignores its context still cannot hold up the probe.
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	return Result{Name: name, Err: err, Latency: time.Since(start)}
+}
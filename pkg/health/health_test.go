@@ -0,0 +1,133 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var errDown = errors.New("down")
+
+func passing(ctx context.Context) error { return nil }
+
+func failing(ctx context.Context) error { return errDown }
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name         string
+		checks       map[string]Check
+		shuttingDown bool
+		wantStatus   string
+		wantErrs     map[string]error
+	}{
+		{
+			name:       "no checks",
+			wantStatus: StatusOK,
+			wantErrs:   map[string]error{},
+		},
+		{
+			name:       "all passing",
+			checks:     map[string]Check{"database": passing, "storage": passing},
+			wantStatus: StatusOK,
+			wantErrs:   map[string]error{"database": nil, "storage": nil},
+		},
+		{
+			name:       "one failing",
+			checks:     map[string]Check{"database": passing, "storage": failing},
+			wantStatus: StatusFailing,
+			wantErrs:   map[string]error{"database": nil, "storage": errDown},
+		},
+		{
+			name:         "shutting down",
+			checks:       map[string]Check{"database": passing},
+			shuttingDown: true,
+			wantStatus:   StatusDegraded,
+			wantErrs:     map[string]error{"database": nil, "shutdown": ErrShuttingDown},
+		},
+		{
+			name:         "failing while shutting down",
+			checks:       map[string]Check{"database": failing},
+			shuttingDown: true,
+			wantStatus:   StatusDegraded,
+			wantErrs:     map[string]error{"database": errDown, "shutdown": ErrShuttingDown},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := NewChecker(DefaultTimeout)
+			for name, check := range tt.checks {
+				checker.Register(name, check)
+			}
+			if tt.shuttingDown {
+				checker.SetShuttingDown()
+			}
+
+			report := checker.Run(context.Background())
+
+			if report.Status != tt.wantStatus || report.Ready() != (tt.wantStatus == StatusOK) {
+				t.Errorf("Run() status = %q, ready %v, want %q", report.Status, report.Ready(), tt.wantStatus)
+			}
+
+			errs := make(map[string]error, len(report.Results))
+			for _, result := range report.Results {
+				errs[result.Name] = result.Err
+			}
+			if !reflect.DeepEqual(errs, tt.wantErrs) {
+				t.Errorf("Run() results = %v, want %v", errs, tt.wantErrs)
+			}
+		})
+	}
+}
+
+func TestRunSortsResults(t *testing.T) {
+	checker := NewChecker(DefaultTimeout)
+	for _, name := range []string{"storage", "database", "search"} {
+		checker.Register(name, passing)
+	}
+
+	var names []string
+	for _, result := range checker.Run(context.Background()).Results {
+		names = append(names, result.Name)
+	}
+
+	if want := []string{"database", "search", "storage"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Run() results = %v, want %v", names, want)
+	}
+}
+
+func TestRunTimeout(t *testing.T) {
+	checker := NewChecker(10 * time.Millisecond)
+
+	// A check that ignores its context must not hold up the report.
+	release := make(chan struct{})
+	defer close(release)
+	checker.Register("stuck", func(ctx context.Context) error {
+		<-release
+		return nil
+	})
+
+	report := checker.Run(context.Background())
+
+	if report.Status != StatusFailing || !errors.Is(report.Results[0].Err, context.DeadlineExceeded) {
+		t.Errorf("Run() = %q, %v, want %q, %v", report.Status, report.Results[0].Err, StatusFailing, context.DeadlineExceeded)
+	}
+}
+
+func TestWorker(t *testing.T) {
+	checker := NewChecker(DefaultTimeout)
+	stopped := checker.Worker("timeline")
+
+	if report := checker.Run(context.Background()); !report.Ready() {
+		t.Errorf("Run() of a running worker = %q, want %q", report.Status, StatusOK)
+	}
+
+	stopped()
+
+	report := checker.Run(context.Background())
+	if report.Ready() || !errors.Is(report.Results[0].Err, ErrWorkerStopped) {
+		t.Errorf("Run() of a stopped worker = %q, %v, want %q, %v", report.Status, report.Results[0].Err, StatusFailing, ErrWorkerStopped)
+	}
+}
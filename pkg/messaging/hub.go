@@ -0,0 +1,96 @@
+package messaging
+
+import "sync"
+
+const (
+	EventMessage = "message"
+	EventRead    = "read"
+
+	// subscriberBuffer is how many events a connection may fall behind
+	// before further events are dropped for it.
+	subscriberBuffer = 32
+)
+
+// Event is pushed to the streaming connections of a user.
+type Event struct {
+	Type string      `json:"type"`
+	Data interface{} `json:"data"`
+}
+
+// Hub fans events out to the streaming connections of each user. A user
+// may hold several connections, one per open client.
+type Hub struct {
+	mu          sync.RWMutex
+	subscribers map[string]map[chan Event]struct{}
+	closed      bool
+}
+
+func NewHub() *Hub {
+	return &Hub{
+		subscribers: make(map[string]map[chan Event]struct{}),
+	}
+}
+
+// Subscribe registers a connection of userID. The returned function has to
+// be called once the connection is closed. The channel is closed when the
+// hub shuts down.
+func (h *Hub) Subscribe(userID string) (<-chan Event, func()) {
+	events := make(chan Event, subscriberBuffer)
+
+	h.mu.Lock()
+	if h.closed {
+		h.mu.Unlock()
+		close(events)
+		return events, func() {}
+	}
+	if h.subscribers[userID] == nil {
+		h.subscribers[userID] = make(map[chan Event]struct{})
+	}
+	h.subscribers[userID][events] = struct{}{}
+	h.mu.Unlock()
+
+	unsubscribe := func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		delete(h.subscribers[userID], events)
+		if len(h.subscribers[userID]) == 0 {
+			delete(h.subscribers, userID)
+		}
+	}
+
+	return events, unsubscribe
+}
+
+// Publish sends event to every connection of userIDs. It never blocks: a
+// connection whose buffer is full misses the event and has to catch up
+// through the message history.
+func (h *Hub) Publish(userIDs []string, event Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, userID := range userIDs {
+		for events := range h.subscribers[userID] {
+			select {
+			case events <- event:
+			default:
+			}
+		}
+	}
+}
+
+// Close ends every connection by closing its channel, so streaming handlers
+// return and the server can finish shutting down. Later subscriptions are
+// closed straight away.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, connections := range h.subscribers {
+		for events := range connections {
+			close(events)
+		}
+	}
+	h.subscribers = make(map[string]map[chan Event]struct{})
+	h.closed = true
+}
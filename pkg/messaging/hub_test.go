@@ -0,0 +1,134 @@
+package messaging
+
+import "testing"
+
+// received drains what is buffered for a connection without blocking.
+func received(events <-chan Event) []Event {
+	var got []Event
+	for {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return got
+			}
+			got = append(got, event)
+		default:
+			return got
+		}
+	}
+}
+
+func TestPublish(t *testing.T) {
+	tests := []struct {
+		name    string
+		userIDs []string
+		want    map[string]int
+	}{
+		{
+			name:    "every connection of the user",
+			userIDs: []string{"alice"},
+			want:    map[string]int{"alice": 1, "bob": 0, "carol": 0},
+		},
+		{
+			name:    "several users",
+			userIDs: []string{"alice", "bob"},
+			want:    map[string]int{"alice": 1, "bob": 1, "carol": 0},
+		},
+		{
+			name:    "user without connections",
+			userIDs: []string{"dave"},
+			want:    map[string]int{"alice": 0, "bob": 0, "carol": 0},
+		},
+		{
+			name: "nobody",
+			want: map[string]int{"alice": 0, "bob": 0, "carol": 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			hub := NewHub()
+
+			// alice has two open clients, both get the event.
+			connections := map[string][]<-chan Event{}
+			for _, userID := range []string{"alice", "alice", "bob", "carol"} {
+				events, unsubscribe := hub.Subscribe(userID)
+				defer unsubscribe()
+				connections[userID] = append(connections[userID], events)
+			}
+
+			hub.Publish(tt.userIDs, Event{Type: EventMessage, Data: "hi"})
+
+			for userID, events := range connections {
+				for i, connection := range events {
+					if got := len(received(connection)); got != tt.want[userID] {
+						t.Errorf("connection %d of %s got %d events, want %d", i, userID, got, tt.want[userID])
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestPublishFullBuffer(t *testing.T) {
+	hub := NewHub()
+	events, unsubscribe := hub.Subscribe("alice")
+	defer unsubscribe()
+
+	// Publishing past the buffer must drop events instead of blocking.
+	for i := 0; i < subscriberBuffer+5; i++ {
+		hub.Publish([]string{"alice"}, Event{Type: EventMessage, Data: i})
+	}
+
+	got := received(events)
+	if len(got) != subscriberBuffer {
+		t.Fatalf("got %d events, want %d", len(got), subscriberBuffer)
+	}
+	if got[0].Data != 0 || got[len(got)-1].Data != subscriberBuffer-1 {
+		t.Errorf("got events %v to %v, want the first %d", got[0].Data, got[len(got)-1].Data, subscriberBuffer)
+	}
+}
+
+func TestUnsubscribe(t *testing.T) {
+	hub := NewHub()
+	kept, unsubscribeKept := hub.Subscribe("alice")
+	defer unsubscribeKept()
+	dropped, unsubscribe := hub.Subscribe("alice")
+
+	unsubscribe()
+	hub.Publish([]string{"alice"}, Event{Type: EventRead})
+
+	if got := len(received(dropped)); got != 0 {
+		t.Errorf("unsubscribed connection got %d events", got)
+	}
+	if got := len(received(kept)); got != 1 {
+		t.Errorf("remaining connection got %d events, want 1", got)
+	}
+
+	unsubscribeKept()
+	if _, ok := hub.subscribers["alice"]; ok {
+		t.Error("user without connections is still subscribed")
+	}
+}
+
+func TestClose(t *testing.T) {
+	hub := NewHub()
+	before, unsubscribe := hub.Subscribe("alice")
+
+	hub.Close()
+
+	if _, ok := <-before; ok {
+		t.Error("connection open before Close() is not closed")
+	}
+
+	after, unsubscribeAfter := hub.Subscribe("alice")
+	if _, ok := <-after; ok {
+		t.Error("connection opened after Close() is not closed")
+	}
+
+	// Neither publishing nor the deferred unsubscribes of the streaming
+	// handlers may panic on closed channels.
+	hub.Publish([]string{"alice"}, Event{Type: EventMessage})
+	unsubscribe()
+	unsubscribeAfter()
+}
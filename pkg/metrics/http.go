@@ -0,0 +1,33 @@
+package metrics
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+	"vilow-be/pkg/recorder"
+
+	"github.com/gorilla/mux"
+)
+
+// Middleware records every request under the path template of its route,
+// such as /in/media/{id}, so ids do not blow up the number of series. It
+// has to be installed with Router.Use to see the matched route.
+func Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil {
+			if template, err := current.GetPathTemplate(); err == nil {
+				route = template
+			}
+		}
+
+		rec := recorder.Wrap(w)
+		start := time.Now()
+
+		next.ServeHTTP(rec, r)
+
+		code := strconv.Itoa(rec.Status())
+		httpRequests.WithLabelValues(route, r.Method, code).Inc()
+		httpDuration.WithLabelValues(route, r.Method, code).Observe(time.Since(start).Seconds())
+	})
+}
@@ -0,0 +1,176 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/steebchen/prisma-client-go/engine"
+)
+
+func TestOperationName(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload interface{}
+		want    string
+	}{
+		{
+			name:    "find unique",
+			payload: engine.GQLRequest{Query: `query {result: findUniqueUser(where: {id: "1"}) {id}}`},
+			want:    "findUniqueUser",
+		},
+		{
+			name:    "mutation",
+			payload: engine.GQLRequest{Query: "mutation {\n  result:  updateOneMedia(data: {}) {id}\n}"},
+			want:    "updateOneMedia",
+		},
+		{
+			name:    "no result alias",
+			payload: engine.GQLRequest{Query: "query {findManyUser {id}}"},
+			want:    "other",
+		},
+		{
+			name:    "not a query",
+			payload: "findUniqueUser",
+			want:    "other",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := OperationName(tt.payload); got != tt.want {
+				t.Errorf("OperationName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	router := mux.NewRouter()
+	router.Use(Middleware)
+	router.HandleFunc("/metrics-test/{id}", func(w http.ResponseWriter, r *http.Request) {
+		if mux.Vars(r)["id"] == "missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("ok"))
+	}).Methods(http.MethodGet)
+
+	tests := []struct {
+		name string
+		path string
+		code string
+	}{
+		{name: "implicit status", path: "/metrics-test/1", code: "200"},
+		{name: "explicit status", path: "/metrics-test/missing", code: "404"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Series are labelled by route template, not by the requested path.
+			requests := httpRequests.WithLabelValues("/metrics-test/{id}", http.MethodGet, tt.code)
+			before := testutil.ToFloat64(requests)
+
+			router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tt.path, nil))
+
+			if got := testutil.ToFloat64(requests) - before; got != 1 {
+				t.Errorf("requests counted = %v, want 1", got)
+			}
+		})
+	}
+}
+
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestInstrumentTransport(t *testing.T) {
+	tests := []struct {
+		name         string
+		method       string
+		body         string
+		status       int
+		err          error
+		wantFailures float64
+		wantBytes    float64
+	}{
+		{name: "upload", method: http.MethodPut, body: "12345", status: http.StatusOK, wantBytes: 5},
+		{name: "download", method: http.MethodGet, status: http.StatusOK},
+		{name: "error status", method: http.MethodPut, body: "12345", status: http.StatusForbidden, wantFailures: 1},
+		{name: "transport error", method: http.MethodGet, err: errors.New("connection refused"), wantFailures: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transport := InstrumentTransport(roundTripFunc(func(r *http.Request) (*http.Response, error) {
+				if tt.err != nil {
+					return nil, tt.err
+				}
+				return &http.Response{StatusCode: tt.status, Body: http.NoBody}, nil
+			}))
+
+			failures := storageFailures.WithLabelValues(tt.method)
+			failuresBefore := testutil.ToFloat64(failures)
+			bytesBefore := testutil.ToFloat64(storageUploadBytes)
+
+			req := httptest.NewRequest(tt.method, "http://minio/bucket/object", strings.NewReader(tt.body))
+			transport.RoundTrip(req)
+
+			if got := testutil.ToFloat64(failures) - failuresBefore; got != tt.wantFailures {
+				t.Errorf("failures counted = %v, want %v", got, tt.wantFailures)
+			}
+			if got := testutil.ToFloat64(storageUploadBytes) - bytesBefore; got != tt.wantBytes {
+				t.Errorf("upload bytes counted = %v, want %v", got, tt.wantBytes)
+			}
+		})
+	}
+}
+
+type fakeEngine struct {
+	engine.Engine
+	err error
+}
+
+func (e fakeEngine) Do(ctx context.Context, payload interface{}, into interface{}) error {
+	return e.err
+}
+
+func (e fakeEngine) Batch(ctx context.Context, payload interface{}, into interface{}) error {
+	return e.err
+}
+
+func TestInstrumentEngine(t *testing.T) {
+	payload := engine.GQLRequest{Query: "query {result: findManyMetricsTest {id}}"}
+
+	tests := []struct {
+		name       string
+		err        error
+		wantErrors float64
+	}{
+		{name: "success"},
+		{name: "failure", err: errors.New("timed out"), wantErrors: 1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			queryErrors := dbErrors.WithLabelValues("findManyMetricsTest")
+			before := testutil.ToFloat64(queryErrors)
+
+			err := InstrumentEngine(fakeEngine{err: tt.err}).Do(context.Background(), payload, nil)
+
+			if err != tt.err {
+				t.Errorf("Do() error = %v, want %v", err, tt.err)
+			}
+			if got := testutil.ToFloat64(queryErrors) - before; got != tt.wantErrors {
+				t.Errorf("errors counted = %v, want %v", got, tt.wantErrors)
+			}
+		})
+	}
+}
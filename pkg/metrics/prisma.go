@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"regexp"
+	"time"
+
+	"github.com/steebchen/prisma-client-go/engine"
+)
+
+// Queries are sent as "query {result: findUniqueUser(...) ...}", the name
+// after result: is the operation and model.
+var operationPattern = regexp.MustCompile(`result:\s*(\w+)`)
+
+// InstrumentEngine wraps the query engine of a Prisma client so every query
+// is timed. Install it with client.Engine = metrics.InstrumentEngine(client.Engine).
+func InstrumentEngine(next engine.Engine) engine.Engine {
+	return &instrumentedEngine{Engine: next}
+}
+
+type instrumentedEngine struct {
+	engine.Engine
+}
+
+func (e *instrumentedEngine) Do(ctx context.Context, payload interface{}, into interface{}) error {
+	start := time.Now()
+	err := e.Engine.Do(ctx, payload, into)
+	observeQuery(OperationName(payload), start, err)
+
+	return err
+}
+
+func (e *instrumentedEngine) Batch(ctx context.Context, payload interface{}, into interface{}) error {
+	start := time.Now()
+	err := e.Engine.Batch(ctx, payload, into)
+	observeQuery("batch", start, err)
+
+	return err
+}
+
+func observeQuery(operation string, start time.Time, err error) {
+	dbDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err != nil {
+		dbErrors.WithLabelValues(operation).Inc()
+	}
+}
+
+// OperationName returns the Prisma operation of a query payload, such as
+// findUniqueUser, or "other" when it cannot tell.
+func OperationName(payload interface{}) string {
+	request, ok := payload.(engine.GQLRequest)
+	if !ok {
+		return "other"
+	}
+
+	match := operationPattern.FindStringSubmatch(request.Query)
+	if match == nil {
+		return "other"
+	}
+
+	return match[1]
+}
@@ -0,0 +1,30 @@
+package metrics
+
+import (
+	"net/http"
+	"time"
+)
+
+// InstrumentTransport wraps the HTTP transport of the MinIO client so every
+// S3 request is timed and uploaded bytes are counted.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return &instrumentedTransport{next: next}
+}
+
+type instrumentedTransport struct {
+	next http.RoundTripper
+}
+
+func (t *instrumentedTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+
+	storageDuration.WithLabelValues(req.Method).Observe(time.Since(start).Seconds())
+	if err != nil || resp.StatusCode >= http.StatusBadRequest {
+		storageFailures.WithLabelValues(req.Method).Inc()
+	} else if req.Method == http.MethodPut && req.ContentLength > 0 {
+		storageUploadBytes.Add(float64(req.ContentLength))
+	}
+
+	return resp, err
+}
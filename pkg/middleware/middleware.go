@@ -4,32 +4,34 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"vilow-be/pkg/apierror"
 	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
 	"vilow-be/pkg/utils"
 	"vilow-be/prisma/db"
 )
 
 type AuthContextKey string
 
-func AuthMiddleware(next http.HandlerFunc, client *db.PrismaClient) http.HandlerFunc {
+func AuthMiddleware(next http.HandlerFunc, client *db.PrismaClient, jwtSecret string) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		authHeader := r.Header.Get("Authorization")
 		userId := r.Header.Get("UserId")
 
 		if authHeader == "" {
-			http.Error(w, "Token not given", http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeUnauthorized, "Token not given"))
 			return
 		}
 
 		tokenString := strings.Split(authHeader, "Bearer ")
 		if len(tokenString) != 2 {
-			http.Error(w, "Invalid Token", http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid token"))
 			return
 		}
 
-		token, err := utils.VerifyToken(tokenString[1], userId)
+		token, err := utils.VerifyToken(tokenString[1], userId, jwtSecret)
 		if err != nil || !token.Valid {
-			http.Error(w, "Invalid Token", http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "Invalid token"))
 			return
 		}
 
@@ -38,24 +40,50 @@ func AuthMiddleware(next http.HandlerFunc, client *db.PrismaClient) http.Handler
 		).Exec(r.Context())
 
 		if err != nil || user == nil {
-			http.Error(w, "User not found: "+err.Error(), http.StatusUnauthorized)
+			apierror.Write(w, r, apierror.New(http.StatusUnauthorized, apierror.CodeInvalidToken, "User not found"))
 			return
 		}
 
 		authContext := dto.AuthContext{
-			UserID:   userId,
-			Name:     user.Name,
-			Email:    user.Email,
-			StrID:    user.StrID,
-			Subjects: user.Subjects,
+			UserID:        userId,
+			Name:          user.Name,
+			Email:         user.Email,
+			StrID:         user.StrID,
+			Subjects:      user.Subjects,
+			HistoryPaused: user.HistoryPaused,
+			Role:          user.Role,
 		}
 
-		ctx := context.WithValue(r.Context(), AuthContextKey("authContext"), authContext)
+		ctx := logging.SetUserID(r.Context(), userId)
+		ctx = context.WithValue(ctx, AuthContextKey("authContext"), authContext)
 
 		next.ServeHTTP(w, r.WithContext(ctx))
 	}
 }
 
+// RequireRole only lets requests through whose user has one of roles. It
+// has to run after AuthMiddleware.
+func RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authContext, ok := r.Context().Value(AuthContextKey("authContext")).(dto.AuthContext)
+			if !ok {
+				apierror.Error(w, r, "AuthContext not found in context", http.StatusInternalServerError)
+				return
+			}
+
+			for _, role := range roles {
+				if authContext.Role == role {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			apierror.Error(w, r, "You do not have permission to access this resource", http.StatusForbidden)
+		})
+	}
+}
+
 func CorsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		// TODO: Replace '*' with specific origin
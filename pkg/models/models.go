@@ -1,65 +1,142 @@
 package models
 
-import "time"
-
-type User struct {
-	ID            string         `json:"id"`
-	Name          string         `json:"name"`
-	Email         string         `json:"email"`
-	StrID         string         `json:"strId"`
-	Description   string         `json:"description"`
-	Medias        []Media        `json:"medias"`
-	Password      string         `json:"password"`
-	Followers     []Follow       `json:"followers"`
-	Following     []Follow       `json:"following"`
-	Notifications []Notification `json:"notifications"`
-	Likes         []Like         `json:"likes"`
-	Dislikes      []Dislike      `json:"dislikes"`
-	Comments      []Comment      `json:"comments"`
-	Subjects      []string       `json:"subjects"`
-}
-
-type Media struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Path        string    `json:"path"`
-	Description string    `json:"description"`
-	Subjects    []string  `json:"subjects"`
-	User        User      `json:"user"`
-	UserID      string    `json:"userId"`
-	Likes       []Like    `json:"likes"`
-	Dislikes    []Dislike `json:"dislikes"`
-	Comments    []Comment `json:"comments"`
-}
-
-type Follow struct {
-	ID        string `json:"id"`
-	Follower  User   `json:"follower"`
-	Following User   `json:"following"`
-}
-
-type Notification struct {
-	ID        string    `json:"id"`
-	User      User      `json:"user"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
-}
-
-type Like struct {
-	ID    string `json:"id"`
-	User  User   `json:"user"`
-	Media Media  `json:"media"`
-}
-
-type Dislike struct {
-	ID    string `json:"id"`
-	User  User   `json:"user"`
-	Media Media  `json:"media"`
-}
-
-type Comment struct {
-	ID      string `json:"id"`
-	User    User   `json:"user"`
-	Media   Media  `json:"media"`
+// Signup is the body of a sign-up.
+type Signup struct {
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	StrID       string `json:"strId"`
+	Description string `json:"description"`
+}
+
+// UserUpdate is the body of a profile update. Empty fields and a missing
+// isPrivate are left unchanged.
+type UserUpdate struct {
+	Name        string `json:"name"`
+	Email       string `json:"email"`
+	Password    string `json:"password"`
+	StrID       string `json:"strId"`
+	Description string `json:"description"`
+	IsPrivate   *bool  `json:"isPrivate"`
+}
+
+// Credentials is the body of a login.
+type Credentials struct {
+	Email    string `json:"email"`
+	Password string `json:"password"`
+}
+
+// CommentInput is the body of a new comment.
+type CommentInput struct {
 	Content string `json:"content"`
 }
+
+const (
+	PlaylistPublic   = "public"
+	PlaylistUnlisted = "unlisted"
+	PlaylistPrivate  = "private"
+
+	PlaylistKindCustom     = "custom"
+	PlaylistKindWatchLater = "watch_later"
+)
+
+// PlaylistInput is the body of a playlist creation or update.
+type PlaylistInput struct {
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	Visibility  string `json:"visibility"`
+}
+
+// PlaylistItemInput is the body of a media added to a playlist.
+type PlaylistItemInput struct {
+	MediaID string `json:"mediaId"`
+}
+
+type PlaylistOrder struct {
+	MediaIDs []string `json:"mediaIds"`
+}
+
+type PlaybackEvent struct {
+	Event    string  `json:"event"`
+	Position float64 `json:"position"`
+	Duration float64 `json:"duration"`
+}
+
+type HistorySettings struct {
+	Paused bool `json:"paused"`
+}
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
+const (
+	ReportTargetMedia   = "media"
+	ReportTargetComment = "comment"
+	ReportTargetUser    = "user"
+
+	ReportOpen      = "open"
+	ReportDismissed = "dismissed"
+	ReportActioned  = "actioned"
+
+	ModerationDismiss = "dismiss"
+	ModerationHide    = "hide"
+	ModerationRemove  = "remove"
+)
+
+var ReportReasons = []string{"spam", "harassment", "hate", "violence", "sexual", "misinformation", "copyright", "other"}
+
+type Report struct {
+	TargetType string `json:"targetType"`
+	TargetID   string `json:"targetId"`
+	Reason     string `json:"reason"`
+	Notes      string `json:"notes"`
+}
+
+type ModerationDecision struct {
+	Action string `json:"action"`
+	Notes  string `json:"notes"`
+}
+
+type RoleUpdate struct {
+	Role string `json:"role"`
+}
+
+const (
+	ReactionLike    = "like"
+	ReactionDislike = "dislike"
+)
+
+type Reaction struct {
+	Type string `json:"type"`
+}
+
+// Limits of the fields users and media are created with.
+const (
+	MaxUserNameLength         = 50
+	MaxUserDescriptionLength  = 500
+	MaxMediaNameLength        = 100
+	MaxMediaDescriptionLength = 5000
+	MaxMediaSubjects          = 10
+	MaxSubjectLength          = 30
+)
+
+// MaxConversationSize caps the participants of a group conversation,
+// including its creator.
+const MaxConversationSize = 10
+
+type Conversation struct {
+	Title        string   `json:"title"`
+	Participants []string `json:"participants"`
+}
+
+type Message struct {
+	Content string `json:"content"`
+	MediaID string `json:"mediaId"`
+}
+
+type ReadReceipt struct {
+	MessageID string `json:"messageId"`
+}
@@ -0,0 +1,99 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// The document is maintained by hand in YAML, which is easier to review,
+// and served as JSON.
+//
+//go:embed openapi.yaml
+var source []byte
+
+//go:embed docs.html
+var docsPage []byte
+
+var methods = map[string]bool{
+	"get": true, "put": true, "post": true, "delete": true,
+	"options": true, "head": true, "patch": true, "trace": true,
+}
+
+// Load converts a YAML OpenAPI document to JSON.
+func Load(data []byte) ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse OpenAPI document: %w", err)
+	}
+
+	// JSON objects only have string keys, status codes have to be quoted.
+	raw, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("convert OpenAPI document to JSON: %w", err)
+	}
+
+	return raw, nil
+}
+
+// Spec is the OpenAPI document of the API, converted to JSON once at
+// startup.
+type Spec struct {
+	document []byte
+}
+
+// New loads the document embedded in the binary. A malformed document is
+// reported as an error rather than a panic, so startup can fail cleanly.
+func New() (*Spec, error) {
+	document, err := Load(source)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Spec{document: document}, nil
+}
+
+// Operations returns every operation of the document as "METHOD /path",
+// sorted, with paths in the {param} form mux templates use.
+func (s *Spec) Operations() ([]string, error) {
+	var doc struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}
+	if err := json.Unmarshal(s.document, &doc); err != nil {
+		return nil, err
+	}
+
+	operations := []string{}
+	for path, item := range doc.Paths {
+		for method := range item {
+			if methods[method] {
+				operations = append(operations, strings.ToUpper(method)+" "+path)
+			}
+		}
+	}
+	sort.Strings(operations)
+
+	return operations, nil
+}
+
+// Handler serves the document.
+func (s *Spec) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(s.document)
+	})
+}
+
+// DocsHandler serves a page rendering the document served next to it at
+// openapi.json. The renderer is loaded from a CDN.
+func DocsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write(docsPage)
+	})
+}
@@ -0,0 +1,54 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "status codes become string keys",
+			data: "paths:\n  /healthz:\n    get:\n      responses:\n        200:\n          description: OK\n",
+			want: `{"paths":{"/healthz":{"get":{"responses":{"200":{"description":"OK"}}}}}}`,
+		},
+		{
+			name:    "malformed YAML",
+			data:    "paths:\n  /healthz: [get\n",
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Load([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && string(got) != tt.want {
+				t.Errorf("Load() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	spec, err := New()
+	if err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+
+	if !json.Valid(spec.document) {
+		t.Error("New() document is not valid JSON")
+	}
+
+	operations, err := spec.Operations()
+	if err != nil || len(operations) == 0 {
+		t.Errorf("Operations() = %d operations, %v", len(operations), err)
+	}
+}
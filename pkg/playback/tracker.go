@@ -0,0 +1,287 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"math"
+	"sync"
+	"time"
+	"vilow-be/pkg/logging"
+	"vilow-be/prisma/db"
+
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+const (
+	EventStart    = "start"
+	EventProgress = "progress"
+	EventComplete = "complete"
+
+	// DefaultViewWindow is how long a viewer has to wait before watching the
+	// same media again counts as another view.
+	DefaultViewWindow = 30 * time.Minute
+	// DefaultFlushInterval is how often pending counters are written to the
+	// database.
+	DefaultFlushInterval = 10 * time.Second
+	// MaxDuration is the longest media length, in seconds, a player may
+	// report.
+	MaxDuration = 12 * 60 * 60
+
+	// maxHeartbeatGap caps the watch time a single heartbeat can add, so a
+	// client that goes quiet and reports a far position later is not credited
+	// for the whole gap.
+	maxHeartbeatGap = time.Minute
+)
+
+var ErrUnknownEvent = errors.New("event must be start, progress or complete")
+
+type session struct {
+	position  float64
+	at        time.Time
+	countedAt time.Time
+}
+
+type counters struct {
+	views     int
+	watchTime float64
+	viewers   map[string]bool
+}
+
+// dayKey groups counters per media and UTC day, which is the granularity
+// analytics are reported in.
+type dayKey struct {
+	mediaID string
+	day     time.Time
+}
+
+// Tracker deduplicates views and accumulates watch time in memory. Counters
+// are applied to the media records and their daily stats in batches by Run.
+type Tracker struct {
+	client     *db.PrismaClient
+	viewWindow time.Duration
+	interval   time.Duration
+
+	mu       sync.Mutex
+	sessions map[string]*session
+	pending  map[dayKey]*counters
+}
+
+func NewTracker(client *db.PrismaClient, viewWindow, interval time.Duration) *Tracker {
+	return &Tracker{
+		client:     client,
+		viewWindow: viewWindow,
+		interval:   interval,
+		sessions:   make(map[string]*session),
+		pending:    make(map[dayKey]*counters),
+	}
+}
+
+// Record registers a playback event of userID on mediaID at position
+// seconds into the video and reports whether it counted as a new view.
+func (t *Tracker) Record(userID, mediaID, event string, position float64, now time.Time) (bool, error) {
+	if event != EventStart && event != EventProgress && event != EventComplete {
+		return false, ErrUnknownEvent
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	s, ok := t.sessions[userID+"/"+mediaID]
+	if !ok {
+		s = &session{}
+		t.sessions[userID+"/"+mediaID] = s
+	}
+
+	key := dayKey{mediaID: mediaID, day: Day(now)}
+
+	counted := false
+	if event == EventStart {
+		if s.countedAt.IsZero() || now.Sub(s.countedAt) >= t.viewWindow {
+			s.countedAt = now
+			c := t.counters(key)
+			c.views++
+			c.viewers[userID] = true
+			counted = true
+		}
+	} else if !s.at.IsZero() {
+		// Credit the smaller of wall-clock time and playback progress, so
+		// seeking forward does not count as watching.
+		elapsed := now.Sub(s.at)
+		if elapsed > maxHeartbeatGap {
+			elapsed = maxHeartbeatGap
+		}
+
+		if watched := math.Min(elapsed.Seconds(), position-s.position); watched > 0 {
+			t.counters(key).watchTime += watched
+		}
+	}
+
+	s.position = position
+	s.at = now
+
+	return counted, nil
+}
+
+// Day truncates t to the UTC day it falls in.
+func Day(t time.Time) time.Time {
+	return t.UTC().Truncate(24 * time.Hour)
+}
+
+func (t *Tracker) counters(key dayKey) *counters {
+	c, ok := t.pending[key]
+	if !ok {
+		c = &counters{viewers: make(map[string]bool)}
+		t.pending[key] = c
+	}
+	return c
+}
+
+// Run flushes pending counters every interval until ctx is cancelled, then
+// flushes once more so nothing recorded before shutdown is lost.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			t.Flush(context.Background(), time.Now())
+			return
+		case now := <-ticker.C:
+			t.Flush(ctx, now)
+			t.prune(now)
+		}
+	}
+}
+
+// Flush writes the pending counters to the media records and their daily
+// stats. Watch time is stored in whole seconds; the remainder stays pending
+// until the day is over.
+func (t *Tracker) Flush(ctx context.Context, now time.Time) {
+	t.mu.Lock()
+	pending := t.pending
+	t.pending = make(map[dayKey]*counters, len(pending))
+	t.mu.Unlock()
+
+	today := Day(now)
+	for key, c := range pending {
+		seconds := math.Floor(c.watchTime)
+		if c.views == 0 && seconds == 0 {
+			t.keepRemainder(key, c.watchTime, today)
+			continue
+		}
+
+		err := t.write(ctx, key, c.views, int(seconds), c.viewers)
+		if errors.Is(err, db.ErrNotFound) {
+			continue
+		} else if err != nil {
+			logging.FromContext(ctx).Error("Error flushing playback counters", "media_id", key.mediaID, "error", err)
+			t.restore(key, c)
+			continue
+		}
+
+		t.keepRemainder(key, c.watchTime-seconds, today)
+	}
+}
+
+// keepRemainder puts the sub-second watch time of key back into the pending
+// set. A past day gets no more heartbeats to round it up to a second, so its
+// remainder is dropped instead of staying pending forever.
+func (t *Tracker) keepRemainder(key dayKey, watchTime float64, today time.Time) {
+	if key.day.Before(today) {
+		return
+	}
+
+	t.restore(key, &counters{watchTime: watchTime})
+}
+
+// write adds the counters of key in one transaction, so a failed flush can
+// be retried as a whole without counting anything twice.
+func (t *Tracker) write(ctx context.Context, key dayKey, views, watchTime int, viewers map[string]bool) error {
+	ops := []transaction.Param{
+		t.client.Media.FindUnique(
+			db.Media.ID.Equals(key.mediaID),
+		).Update(
+			db.Media.ViewCount.Increment(views),
+			db.Media.WatchTime.Increment(watchTime),
+		).Tx(),
+		t.client.MediaDailyStat.UpsertOne(
+			db.MediaDailyStat.MediaIDDay(
+				db.MediaDailyStat.MediaID.Equals(key.mediaID),
+				db.MediaDailyStat.Day.Equals(key.day),
+			),
+		).Create(
+			db.MediaDailyStat.Media.Link(
+				db.Media.ID.Equals(key.mediaID),
+			),
+			db.MediaDailyStat.Day.Set(key.day),
+			db.MediaDailyStat.Views.Set(views),
+			db.MediaDailyStat.WatchTime.Set(watchTime),
+		).Update(
+			db.MediaDailyStat.Views.Increment(views),
+			db.MediaDailyStat.WatchTime.Increment(watchTime),
+		).Tx(),
+	}
+
+	// One record per viewer and day makes unique viewers a plain count.
+	for userID := range viewers {
+		ops = append(ops, t.client.MediaDailyViewer.UpsertOne(
+			db.MediaDailyViewer.MediaIDUserIDDay(
+				db.MediaDailyViewer.MediaID.Equals(key.mediaID),
+				db.MediaDailyViewer.UserID.Equals(userID),
+				db.MediaDailyViewer.Day.Equals(key.day),
+			),
+		).Create(
+			db.MediaDailyViewer.Media.Link(
+				db.Media.ID.Equals(key.mediaID),
+			),
+			db.MediaDailyViewer.UserID.Set(userID),
+			db.MediaDailyViewer.Day.Set(key.day),
+		).Update().Tx())
+	}
+
+	err := t.client.Prisma.Transaction(ops...).Exec(ctx)
+	if err == nil {
+		return nil
+	}
+
+	// A media deleted since it was watched fails the transaction for good,
+	// its counters are dropped rather than retried forever.
+	if _, findErr := t.client.Media.FindUnique(
+		db.Media.ID.Equals(key.mediaID),
+	).Exec(ctx); errors.Is(findErr, db.ErrNotFound) {
+		return db.ErrNotFound
+	}
+
+	return err
+}
+
+// restore puts counters that could not be written back into the pending set.
+func (t *Tracker) restore(key dayKey, c *counters) {
+	if c.views == 0 && c.watchTime == 0 && len(c.viewers) == 0 {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := t.counters(key)
+	pending.views += c.views
+	pending.watchTime += c.watchTime
+	for userID := range c.viewers {
+		pending.viewers[userID] = true
+	}
+}
+
+// prune forgets sessions that can neither dedupe a view nor extend a
+// heartbeat anymore.
+func (t *Tracker) prune(now time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for key, s := range t.sessions {
+		if now.Sub(s.countedAt) >= t.viewWindow && now.Sub(s.at) >= maxHeartbeatGap {
+			delete(t.sessions, key)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package playback
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+type event struct {
+	userID   string
+	event    string
+	position float64
+	after    time.Duration
+}
+
+func TestRecord(t *testing.T) {
+	tests := []struct {
+		name          string
+		events        []event
+		wantCounted   []bool
+		wantViews     int
+		wantWatchTime float64
+	}{
+		{
+			name:        "first start counts",
+			events:      []event{{userID: "u1", event: EventStart}},
+			wantCounted: []bool{true},
+			wantViews:   1,
+		},
+		{
+			name: "restart within the view window",
+			events: []event{
+				{userID: "u1", event: EventStart},
+				{userID: "u1", event: EventStart, after: DefaultViewWindow - time.Second},
+			},
+			wantCounted: []bool{true, false},
+			wantViews:   1,
+		},
+		{
+			name: "restart after the view window",
+			events: []event{
+				{userID: "u1", event: EventStart},
+				{userID: "u1", event: EventStart, after: DefaultViewWindow},
+			},
+			wantCounted: []bool{true, true},
+			wantViews:   2,
+		},
+		{
+			name: "other viewers count on their own",
+			events: []event{
+				{userID: "u1", event: EventStart},
+				{userID: "u2", event: EventStart},
+			},
+			wantCounted: []bool{true, true},
+			wantViews:   2,
+		},
+		{
+			name:        "progress without a start",
+			events:      []event{{userID: "u1", event: EventProgress, position: 10}},
+			wantCounted: []bool{false},
+		},
+		{
+			name: "progress credits playback",
+			events: []event{
+				{userID: "u1", event: EventStart},
+				{userID: "u1", event: EventProgress, position: 10, after: 10 * time.Second},
+				{userID: "u1", event: EventComplete, position: 15.5, after: 6 * time.Second},
+			},
+			wantCounted:   []bool{true, false, false},
+			wantViews:     1,
+			wantWatchTime: 15.5,
+		},
+		{
+			name: "heartbeat gap is capped",
+			events: []event{
+				{userID: "u1", event: EventStart},
+				{userID: "u1", event: EventProgress, position: 600, after: 10 * time.Minute},
+			},
+			wantCounted:   []bool{true, false},
+			wantViews:     1,
+			wantWatchTime: maxHeartbeatGap.Seconds(),
+		},
+		{
+			name: "seeking forward credits wall-clock time",
+			events: []event{
+				{userID: "u1", event: EventStart},
+				{userID: "u1", event: EventProgress, position: 300, after: 5 * time.Second},
+			},
+			wantCounted:   []bool{true, false},
+			wantViews:     1,
+			wantWatchTime: 5,
+		},
+		{
+			name: "seeking back credits nothing",
+			events: []event{
+				{userID: "u1", event: EventStart, position: 100},
+				{userID: "u1", event: EventProgress, position: 20, after: 5 * time.Second},
+			},
+			wantCounted: []bool{true, false},
+			wantViews:   1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := NewTracker(nil, DefaultViewWindow, DefaultFlushInterval)
+
+			now := testNow
+			for i, e := range tt.events {
+				now = now.Add(e.after)
+				counted, err := tracker.Record(e.userID, "m1", e.event, e.position, now)
+				if err != nil {
+					t.Fatalf("Record() error = %v", err)
+				}
+				if counted != tt.wantCounted[i] {
+					t.Errorf("Record() event %d counted = %v, want %v", i, counted, tt.wantCounted[i])
+				}
+			}
+
+			c := tracker.pending[dayKey{mediaID: "m1", day: Day(testNow)}]
+			views, watchTime := 0, 0.0
+			if c != nil {
+				views, watchTime = c.views, c.watchTime
+			}
+			if views != tt.wantViews || math.Abs(watchTime-tt.wantWatchTime) > 1e-9 {
+				t.Errorf("pending views, watch time = %d, %.2f, want %d, %.2f", views, watchTime, tt.wantViews, tt.wantWatchTime)
+			}
+		})
+	}
+}
+
+func TestRecordUnknownEvent(t *testing.T) {
+	tracker := NewTracker(nil, DefaultViewWindow, DefaultFlushInterval)
+
+	if _, err := tracker.Record("u1", "m1", "pause", 0, testNow); !errors.Is(err, ErrUnknownEvent) {
+		t.Errorf("Record() error = %v, want %v", err, ErrUnknownEvent)
+	}
+}
+
+func TestFlushRemainders(t *testing.T) {
+	tracker := NewTracker(nil, DefaultViewWindow, DefaultFlushInterval)
+
+	yesterday := dayKey{mediaID: "m1", day: Day(testNow.Add(-24 * time.Hour))}
+	today := dayKey{mediaID: "m1", day: Day(testNow)}
+	tracker.counters(yesterday).watchTime = 0.4
+	tracker.counters(today).watchTime = 0.6
+
+	tracker.Flush(context.Background(), testNow)
+
+	if _, ok := tracker.pending[yesterday]; ok {
+		t.Error("Flush() kept the remainder of a past day")
+	}
+	if c, ok := tracker.pending[today]; !ok || c.watchTime != 0.6 {
+		t.Errorf("Flush() remainder of today = %+v, want 0.6 seconds", c)
+	}
+}
@@ -0,0 +1,60 @@
+// Package recorder wraps an http.ResponseWriter to capture what the handler
+// wrote, for the middlewares that log and measure responses.
+package recorder
+
+import "net/http"
+
+// Recorder remembers the status code and body size of a response.
+type Recorder struct {
+	http.ResponseWriter
+	status      int
+	bytes       int64
+	wroteHeader bool
+}
+
+// Wrap returns w as a Recorder. A writer that already is one is returned as
+// is, so stacked middlewares share a single wrapper.
+func Wrap(w http.ResponseWriter) *Recorder {
+	if r, ok := w.(*Recorder); ok {
+		return r
+	}
+
+	return &Recorder{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Status is the status code sent, 200 when the handler never set one.
+func (r *Recorder) Status() int {
+	return r.status
+}
+
+// Bytes is the size of the body written so far.
+func (r *Recorder) Bytes() int64 {
+	return r.bytes
+}
+
+func (r *Recorder) WriteHeader(status int) {
+	if !r.wroteHeader {
+		r.status = status
+		r.wroteHeader = true
+	}
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *Recorder) Write(data []byte) (int, error) {
+	r.wroteHeader = true
+	n, err := r.ResponseWriter.Write(data)
+	r.bytes += int64(n)
+	return n, err
+}
+
+// Flush keeps event streams working behind the recorder.
+func (r *Recorder) Flush() {
+	if flusher, ok := r.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Unwrap lets http.ResponseController reach the underlying writer.
+func (r *Recorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
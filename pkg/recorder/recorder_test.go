@@ -0,0 +1,92 @@
+package recorder
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRecorder(t *testing.T) {
+	tests := []struct {
+		name       string
+		handler    http.HandlerFunc
+		wantStatus int
+		wantBytes  int64
+	}{
+		{
+			name:       "nothing written",
+			handler:    func(w http.ResponseWriter, r *http.Request) {},
+			wantStatus: http.StatusOK,
+		},
+		{
+			name: "body only",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("hello"))
+			},
+			wantStatus: http.StatusOK,
+			wantBytes:  5,
+		},
+		{
+			name: "status and body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte("{}"))
+			},
+			wantStatus: http.StatusNotFound,
+			wantBytes:  2,
+		},
+		{
+			name: "only the first status counts",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusCreated)
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantStatus: http.StatusCreated,
+		},
+		{
+			name: "status after the body",
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.Write([]byte("ok"))
+				w.WriteHeader(http.StatusInternalServerError)
+			},
+			wantStatus: http.StatusOK,
+			wantBytes:  2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := Wrap(httptest.NewRecorder())
+			tt.handler(recorder, httptest.NewRequest(http.MethodGet, "/", nil))
+
+			if recorder.Status() != tt.wantStatus || recorder.Bytes() != tt.wantBytes {
+				t.Errorf("Status(), Bytes() = %d, %d, want %d, %d", recorder.Status(), recorder.Bytes(), tt.wantStatus, tt.wantBytes)
+			}
+		})
+	}
+}
+
+func TestWrapReusesRecorder(t *testing.T) {
+	outer := Wrap(httptest.NewRecorder())
+	if inner := Wrap(outer); inner != outer {
+		t.Error("Wrap() wrapped a Recorder again")
+	}
+}
+
+func TestFlushAndUnwrap(t *testing.T) {
+	underlying := httptest.NewRecorder()
+	recorder := Wrap(underlying)
+
+	recorder.Flush()
+	if !underlying.Flushed {
+		t.Error("Flush() did not reach the underlying writer")
+	}
+
+	// httptest.ResponseRecorder has no deadlines, so reaching it through
+	// Unwrap gives ErrNotSupported rather than failing to find a writer.
+	err := http.NewResponseController(recorder).SetWriteDeadline(time.Time{})
+	if err == nil || recorder.Unwrap() != underlying {
+		t.Errorf("SetWriteDeadline() error = %v, Unwrap() = %v", err, recorder.Unwrap())
+	}
+}
@@ -0,0 +1,34 @@
+package search
+
+import (
+	"encoding/base64"
+	"encoding/json"
+)
+
+// cursor marks the last hit of a page by its position in the result
+// ordering, so paging keeps working when that document is removed meanwhile.
+type cursor struct {
+	Score     float64 `json:"s"`
+	CreatedAt int64   `json:"t"`
+	Key       string  `json:"k"`
+}
+
+func encodeCursor(c cursor) string {
+	data, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(s string) (cursor, error) {
+	var c cursor
+
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return c, ErrInvalidCursor
+	}
+
+	if err := json.Unmarshal(data, &c); err != nil || c.Key == "" {
+		return c, ErrInvalidCursor
+	}
+
+	return c, nil
+}
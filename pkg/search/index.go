@@ -0,0 +1,61 @@
+package search
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	KindMedia = "media"
+	KindUser  = "user"
+
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid search cursor")
+
+// Document is the searchable projection of a media or user record. Fields
+// holds the free text per field name, everything else is used for filtering
+// and ordering.
+type Document struct {
+	ID        string
+	Kind      string
+	Fields    map[string]string
+	Subjects  []string
+	OwnerID   string
+	CreatedAt time.Time
+}
+
+// Query describes a search request. Text may be empty, in which case every
+// document passing the filters matches. Uploader and date filters only ever
+// match media documents.
+type Query struct {
+	Text    string
+	Kind    string
+	Subject string
+	OwnerID string
+	From    time.Time
+	To      time.Time
+	Cursor  string
+	Limit   int
+}
+
+type Hit struct {
+	ID    string  `json:"id"`
+	Kind  string  `json:"kind"`
+	Score float64 `json:"score"`
+}
+
+type Page struct {
+	Hits       []Hit  `json:"hits"`
+	NextCursor string `json:"nextCursor"`
+}
+
+// Index is implemented by every search backend. Upsert replaces any document
+// previously stored under the same kind and ID.
+type Index interface {
+	Upsert(doc Document) error
+	Delete(kind, id string) error
+	Search(query Query) (Page, error)
+}
@@ -0,0 +1,382 @@
+package search
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// BM25 tuning, see https://en.wikipedia.org/wiki/Okapi_BM25
+	bm25K1 = 1.2
+	bm25B  = 0.75
+
+	// prefixPenalty scales down terms that only matched as a prefix of what
+	// the user is still typing, so complete words rank first.
+	prefixPenalty = 0.6
+	maxExpansions = 64
+)
+
+// DefaultWeights ranks a hit in a title or handle above one in the free
+// text description. Fields missing from the weights count with weight 1.
+var DefaultWeights = map[string]float64{
+	"name":        3,
+	"strId":       3,
+	"subjects":    2,
+	"description": 1,
+}
+
+type memoryEntry struct {
+	doc      Document
+	lengths  map[string]int
+	terms    []string
+	subjects map[string]bool
+}
+
+// MemoryIndex is an in-process inverted index. It keeps everything in memory
+// and needs no external service, which makes it the default backend.
+type MemoryIndex struct {
+	mu       sync.RWMutex
+	weights  map[string]float64
+	docs     map[string]*memoryEntry
+	postings map[string]map[string]map[string]int
+	terms    []string
+	fieldLen map[string]int
+}
+
+func NewMemoryIndex(weights map[string]float64) *MemoryIndex {
+	if weights == nil {
+		weights = DefaultWeights
+	}
+
+	return &MemoryIndex{
+		weights:  weights,
+		docs:     make(map[string]*memoryEntry),
+		postings: make(map[string]map[string]map[string]int),
+		fieldLen: make(map[string]int),
+	}
+}
+
+func docKey(kind, id string) string {
+	return kind + ":" + id
+}
+
+func (idx *MemoryIndex) Upsert(doc Document) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	key := docKey(doc.Kind, doc.ID)
+	idx.remove(key)
+
+	entry := &memoryEntry{
+		doc:      doc,
+		lengths:  make(map[string]int, len(doc.Fields)),
+		subjects: make(map[string]bool, len(doc.Subjects)),
+	}
+
+	for _, subject := range doc.Subjects {
+		entry.subjects[Normalize(subject)] = true
+	}
+
+	seen := make(map[string]bool)
+	for field, text := range doc.Fields {
+		tokens := Tokenize(text)
+		entry.lengths[field] = len(tokens)
+		idx.fieldLen[field] += len(tokens)
+
+		for _, term := range tokens {
+			docs, ok := idx.postings[term]
+			if !ok {
+				docs = make(map[string]map[string]int)
+				idx.postings[term] = docs
+				idx.insertTerm(term)
+			}
+
+			fields, ok := docs[key]
+			if !ok {
+				fields = make(map[string]int)
+				docs[key] = fields
+			}
+			fields[field]++
+
+			if !seen[term] {
+				seen[term] = true
+				entry.terms = append(entry.terms, term)
+			}
+		}
+	}
+
+	idx.docs[key] = entry
+	return nil
+}
+
+func (idx *MemoryIndex) Delete(kind, id string) error {
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.remove(docKey(kind, id))
+	return nil
+}
+
+// remove drops a document and its postings. The caller must hold the write lock.
+func (idx *MemoryIndex) remove(key string) {
+	entry, ok := idx.docs[key]
+	if !ok {
+		return
+	}
+
+	for field, length := range entry.lengths {
+		idx.fieldLen[field] -= length
+	}
+
+	for _, term := range entry.terms {
+		docs := idx.postings[term]
+		delete(docs, key)
+		if len(docs) == 0 {
+			delete(idx.postings, term)
+			idx.deleteTerm(term)
+		}
+	}
+
+	delete(idx.docs, key)
+}
+
+func (idx *MemoryIndex) insertTerm(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	idx.terms = append(idx.terms, "")
+	copy(idx.terms[i+1:], idx.terms[i:])
+	idx.terms[i] = term
+}
+
+func (idx *MemoryIndex) deleteTerm(term string) {
+	i := sort.SearchStrings(idx.terms, term)
+	if i < len(idx.terms) && idx.terms[i] == term {
+		idx.terms = append(idx.terms[:i], idx.terms[i+1:]...)
+	}
+}
+
+type scoredEntry struct {
+	key   string
+	entry *memoryEntry
+	score float64
+}
+
+func (idx *MemoryIndex) Search(query Query) (Page, error) {
+	var after *cursor
+	if query.Cursor != "" {
+		c, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return Page{}, err
+		}
+		after = &c
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var matches []scoredEntry
+
+	tokens := Tokenize(query.Text)
+	if len(tokens) == 0 {
+		for key, entry := range idx.docs {
+			if idx.accepts(entry, query) {
+				matches = append(matches, scoredEntry{key: key, entry: entry})
+			}
+		}
+	} else {
+		matches = idx.match(tokens, endsInWord(query.Text), query)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return ranksBefore(matches[i], matches[j])
+	})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matches), func(i int) bool {
+			return isAfterCursor(matches[i], *after)
+		})
+	}
+
+	page := Page{Hits: []Hit{}}
+	end := start + limit
+	if end > len(matches) {
+		end = len(matches)
+	}
+
+	for _, match := range matches[start:end] {
+		page.Hits = append(page.Hits, Hit{
+			ID:    match.entry.doc.ID,
+			Kind:  match.entry.doc.Kind,
+			Score: match.score,
+		})
+	}
+
+	if end < len(matches) {
+		last := matches[end-1]
+		page.NextCursor = encodeCursor(cursor{
+			Score:     last.score,
+			CreatedAt: last.entry.doc.CreatedAt.UnixMicro(),
+			Key:       last.key,
+		})
+	}
+
+	return page, nil
+}
+
+// match scores every document containing all tokens. The last token also
+// matches as a prefix while the user is still typing it.
+func (idx *MemoryIndex) match(tokens []string, prefixLast bool, query Query) []scoredEntry {
+	var scores map[string]float64
+
+	for i, token := range tokens {
+		tokenScores := make(map[string]float64)
+
+		idx.scoreTerm(token, 1, tokenScores)
+		if prefixLast && i == len(tokens)-1 {
+			for _, term := range idx.expand(token) {
+				idx.scoreTerm(term, prefixPenalty, tokenScores)
+			}
+		}
+
+		if scores == nil {
+			scores = tokenScores
+			continue
+		}
+
+		for key, score := range scores {
+			tokenScore, ok := tokenScores[key]
+			if !ok {
+				delete(scores, key)
+				continue
+			}
+			scores[key] = score + tokenScore
+		}
+	}
+
+	matches := make([]scoredEntry, 0, len(scores))
+	for key, score := range scores {
+		entry := idx.docs[key]
+		if idx.accepts(entry, query) {
+			matches = append(matches, scoredEntry{key: key, entry: entry, score: score})
+		}
+	}
+
+	return matches
+}
+
+// scoreTerm keeps, per document, the best BM25 score of any term matched
+// for the current token.
+func (idx *MemoryIndex) scoreTerm(term string, factor float64, scores map[string]float64) {
+	docs, ok := idx.postings[term]
+	if !ok {
+		return
+	}
+
+	total := float64(len(idx.docs))
+	idf := math.Log(1 + (total-float64(len(docs))+0.5)/(float64(len(docs))+0.5))
+
+	for key, fields := range docs {
+		entry := idx.docs[key]
+
+		score := 0.0
+		for field, tf := range fields {
+			weight, ok := idx.weights[field]
+			if !ok {
+				weight = 1
+			}
+
+			avgLen := float64(idx.fieldLen[field]) / total
+			if avgLen == 0 {
+				avgLen = 1
+			}
+
+			norm := 1 - bm25B + bm25B*float64(entry.lengths[field])/avgLen
+			score += weight * idf * float64(tf) * (bm25K1 + 1) / (float64(tf) + bm25K1*norm)
+		}
+
+		score *= factor
+		if score > scores[key] {
+			scores[key] = score
+		}
+	}
+}
+
+// expand lists indexed terms that start with prefix, excluding prefix itself.
+func (idx *MemoryIndex) expand(prefix string) []string {
+	var terms []string
+
+	for i := sort.SearchStrings(idx.terms, prefix); i < len(idx.terms); i++ {
+		term := idx.terms[i]
+		if !strings.HasPrefix(term, prefix) || len(terms) == maxExpansions {
+			break
+		}
+		if term != prefix {
+			terms = append(terms, term)
+		}
+	}
+
+	return terms
+}
+
+func (idx *MemoryIndex) accepts(entry *memoryEntry, query Query) bool {
+	doc := entry.doc
+
+	if query.Kind != "" && doc.Kind != query.Kind {
+		return false
+	}
+	if query.Subject != "" && !entry.subjects[Normalize(query.Subject)] {
+		return false
+	}
+	if query.OwnerID != "" && doc.OwnerID != query.OwnerID {
+		return false
+	}
+	if !query.From.IsZero() && (doc.CreatedAt.IsZero() || doc.CreatedAt.Before(query.From)) {
+		return false
+	}
+	if !query.To.IsZero() && (doc.CreatedAt.IsZero() || doc.CreatedAt.After(query.To)) {
+		return false
+	}
+
+	return true
+}
+
+// ranksBefore orders by score, then newest first, then by key so pages are
+// deterministic between requests.
+func ranksBefore(a, b scoredEntry) bool {
+	if a.score != b.score {
+		return a.score > b.score
+	}
+	if ta, tb := a.entry.doc.CreatedAt.UnixMicro(), b.entry.doc.CreatedAt.UnixMicro(); ta != tb {
+		return ta > tb
+	}
+	return a.key < b.key
+}
+
+func isAfterCursor(match scoredEntry, c cursor) bool {
+	if match.score != c.Score {
+		return match.score < c.Score
+	}
+	if createdAt := match.entry.doc.CreatedAt.UnixMicro(); createdAt != c.CreatedAt {
+		return createdAt < c.CreatedAt
+	}
+	return match.key > c.Key
+}
+
+// endsInWord reports whether the query stops in the middle of a word, in
+// which case its last token is treated as a prefix.
+func endsInWord(text string) bool {
+	r, _ := utf8.DecodeLastRuneInString(text)
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,199 @@
+package search
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenize(t *testing.T) {
+	tests := []struct {
+		input string
+		want  []string
+	}{
+		{input: "Música Brasileira", want: []string{"musica", "brasileira"}},
+		{input: "go-lang, 2024!", want: []string{"go", "lang", "2024"}},
+		{input: "ÇÃO", want: []string{"cao"}},
+		{input: "  ", want: []string{}},
+		{input: "", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got := Tokenize(tt.input)
+			if len(got) == 0 && len(tt.want) == 0 {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tokenize(%q) = %q, want %q", tt.input, got, tt.want)
+			}
+		})
+	}
+}
+
+func testIndex(t *testing.T) *MemoryIndex {
+	t.Helper()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	docs := []Document{
+		{ID: "m1", Kind: KindMedia, Fields: map[string]string{"name": "Go tutorial", "description": "Learn the basics"}, Subjects: []string{"Programming"}, OwnerID: "u1", CreatedAt: base},
+		{ID: "m2", Kind: KindMedia, Fields: map[string]string{"name": "Cooking pasta", "description": "A go-to recipe"}, Subjects: []string{"Food"}, OwnerID: "u2", CreatedAt: base.AddDate(0, 0, 1)},
+		{ID: "m3", Kind: KindMedia, Fields: map[string]string{"name": "Golang concurrency", "description": "Goroutines explained"}, Subjects: []string{"programming"}, OwnerID: "u1", CreatedAt: base.AddDate(0, 0, 2)},
+		{ID: "u1", Kind: KindUser, Fields: map[string]string{"name": "Gopher", "strId": "gopher"}},
+	}
+
+	idx := NewMemoryIndex(nil)
+	for _, doc := range docs {
+		if err := idx.Upsert(doc); err != nil {
+			t.Fatalf("Upsert(%s) error = %v", doc.ID, err)
+		}
+	}
+
+	return idx
+}
+
+func hitIDs(page Page) []string {
+	ids := []string{}
+	for _, hit := range page.Hits {
+		ids = append(ids, hit.Kind+":"+hit.ID)
+	}
+	return ids
+}
+
+func TestMemoryIndexSearch(t *testing.T) {
+	tests := []struct {
+		name  string
+		query Query
+		want  []string
+	}{
+		{
+			name:  "title outranks description",
+			query: Query{Text: "go "},
+			want:  []string{"media:m1", "media:m2"},
+		},
+		{
+			name:  "last word matches as a prefix",
+			query: Query{Text: "concur"},
+			want:  []string{"media:m3"},
+		},
+		{
+			name:  "every word must match",
+			query: Query{Text: "golang goroutines"},
+			want:  []string{"media:m3"},
+		},
+		{
+			name:  "diacritics are ignored",
+			query: Query{Text: "cóoking "},
+			want:  []string{"media:m2"},
+		},
+		{
+			name:  "kind filter",
+			query: Query{Text: "gopher", Kind: KindUser},
+			want:  []string{"user:u1"},
+		},
+		{
+			name:  "subject filter is case insensitive",
+			query: Query{Subject: "PROGRAMMING"},
+			want:  []string{"media:m3", "media:m1"},
+		},
+		{
+			name:  "owner filter",
+			query: Query{Text: "go", OwnerID: "u2"},
+			want:  []string{"media:m2"},
+		},
+		{
+			name:  "date range skips documents without a date",
+			query: Query{From: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+			want:  []string{"media:m3", "media:m2"},
+		},
+		{
+			name:  "no match",
+			query: Query{Text: "python "},
+			want:  []string{},
+		},
+	}
+
+	idx := testIndex(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, err := idx.Search(tt.query)
+			if err != nil {
+				t.Fatalf("Search() error = %v", err)
+			}
+			if got := hitIDs(page); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryIndexPaging(t *testing.T) {
+	idx := testIndex(t)
+
+	all, err := idx.Search(Query{Kind: KindMedia})
+	if err != nil {
+		t.Fatalf("Search() error = %v", err)
+	}
+
+	got := []string{}
+	query := Query{Kind: KindMedia, Limit: 1}
+	for {
+		page, err := idx.Search(query)
+		if err != nil {
+			t.Fatalf("Search() error = %v", err)
+		}
+		got = append(got, hitIDs(page)...)
+
+		if page.NextCursor == "" {
+			break
+		}
+		// Removing the last hit of a page must not break the next one.
+		if err := idx.Delete(page.Hits[0].Kind, page.Hits[0].ID); err != nil {
+			t.Fatalf("Delete() error = %v", err)
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	if want := hitIDs(all); !reflect.DeepEqual(got, want) {
+		t.Errorf("paged hits = %v, want %v", got, want)
+	}
+}
+
+func TestMemoryIndexUpsertReplaces(t *testing.T) {
+	idx := testIndex(t)
+
+	err := idx.Upsert(Document{ID: "m1", Kind: KindMedia, Fields: map[string]string{"name": "Rust tutorial"}})
+	if err != nil {
+		t.Fatalf("Upsert() error = %v", err)
+	}
+
+	tests := []struct {
+		text string
+		want []string
+	}{
+		{text: "rust ", want: []string{"media:m1"}},
+		{text: "basics ", want: []string{}},
+	}
+
+	for _, tt := range tests {
+		page, err := idx.Search(Query{Text: tt.text})
+		if err != nil {
+			t.Fatalf("Search(%q) error = %v", tt.text, err)
+		}
+		if got := hitIDs(page); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestMemoryIndexInvalidCursor(t *testing.T) {
+	idx := testIndex(t)
+
+	for _, value := range []string{"%%%", "bm90IGpzb24", "e30"} {
+		_, err := idx.Search(Query{Cursor: value})
+		if !errors.Is(err, ErrInvalidCursor) {
+			t.Errorf("Search(cursor %q) error = %v, want %v", value, err, ErrInvalidCursor)
+		}
+	}
+}
@@ -0,0 +1,378 @@
+package timeline
+
+import (
+	"context"
+	"log/slog"
+	"sort"
+	"time"
+	"vilow-be/pkg/logging"
+	"vilow-be/prisma/db"
+
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 50
+
+	// DefaultFanoutLimit is the follower count above which a creator's uploads
+	// are no longer copied into every follower's timeline but merged in when
+	// the timeline is read.
+	DefaultFanoutLimit = 10000
+	// DefaultBackfill is how many recent uploads are copied into a timeline
+	// when its owner follows someone new.
+	DefaultBackfill = 50
+
+	writeBatchSize = 100
+	queueSize      = 1024
+)
+
+type jobKind int
+
+const (
+	jobPublish jobKind = iota
+	jobBackfill
+	jobUnfollow
+)
+
+type job struct {
+	kind       jobKind
+	mediaID    string
+	authorID   string
+	followerID string
+	createdAt  time.Time
+}
+
+// Service maintains the materialised "following" timelines. Writes are
+// queued and applied by Run, so publishing never waits on the fan-out.
+type Service struct {
+	client      *db.PrismaClient
+	fanoutLimit int
+	backfill    int
+	jobs        chan job
+}
+
+type Entry struct {
+	MediaID   string
+	AuthorID  string
+	CreatedAt time.Time
+}
+
+type Page struct {
+	Entries    []Entry
+	NextCursor string
+}
+
+func NewService(client *db.PrismaClient, fanoutLimit, backfill int) *Service {
+	return &Service{
+		client:      client,
+		fanoutLimit: fanoutLimit,
+		backfill:    backfill,
+		jobs:        make(chan job, queueSize),
+	}
+}
+
+// Run applies queued timeline writes until ctx is cancelled, then applies
+// whatever is still queued before returning.
+func (s *Service) Run(ctx context.Context) {
+	logger := logging.FromContext(ctx)
+
+	for {
+		select {
+		case <-ctx.Done():
+			s.drain(logger)
+			return
+		case j := <-s.jobs:
+			if err := s.handle(ctx, j); err != nil {
+				logger.Error("Error updating timelines", "media_id", j.mediaID, "author_id", j.authorID, "error", err)
+			}
+		}
+	}
+}
+
+func (s *Service) drain(logger *slog.Logger) {
+	for {
+		select {
+		case j := <-s.jobs:
+			if err := s.handle(context.Background(), j); err != nil {
+				logger.Error("Error updating timelines", "media_id", j.mediaID, "author_id", j.authorID, "error", err)
+			}
+		default:
+			return
+		}
+	}
+}
+
+func (s *Service) enqueue(ctx context.Context, j job) {
+	select {
+	case s.jobs <- j:
+	case <-ctx.Done():
+		logging.FromContext(ctx).Warn("Timeline update dropped", "media_id", j.mediaID, "author_id", j.authorID, "error", ctx.Err())
+	}
+}
+
+// Publish pushes a new upload into its author's followers' timelines.
+func (s *Service) Publish(ctx context.Context, media *db.MediaModel) {
+	s.enqueue(ctx, job{kind: jobPublish, mediaID: media.ID, authorID: media.UserID, createdAt: media.CreatedAt})
+}
+
+// Follow backfills followerID's timeline with authorID's recent uploads.
+func (s *Service) Follow(ctx context.Context, followerID, authorID string) {
+	s.enqueue(ctx, job{kind: jobBackfill, followerID: followerID, authorID: authorID})
+}
+
+// Unfollow removes authorID's uploads from followerID's timeline.
+func (s *Service) Unfollow(ctx context.Context, followerID, authorID string) {
+	s.enqueue(ctx, job{kind: jobUnfollow, followerID: followerID, authorID: authorID})
+}
+
+func (s *Service) handle(ctx context.Context, j job) error {
+	switch j.kind {
+	case jobPublish:
+		return s.fanOut(ctx, j)
+	case jobBackfill:
+		return s.backfillFollower(ctx, j)
+	case jobUnfollow:
+		_, err := s.client.TimelineEntry.FindMany(
+			db.TimelineEntry.UserID.Equals(j.followerID),
+			db.TimelineEntry.AuthorID.Equals(j.authorID),
+		).Delete().Exec(ctx)
+		return err
+	}
+	return nil
+}
+
+func (s *Service) isFanoutOnRead(ctx context.Context, authorID string) (bool, error) {
+	author, err := s.client.User.FindUnique(
+		db.User.ID.Equals(authorID),
+	).Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return author.FollowerCount > s.fanoutLimit, nil
+}
+
+func (s *Service) fanOut(ctx context.Context, j job) error {
+	onRead, err := s.isFanoutOnRead(ctx, j.authorID)
+	if err != nil || onRead {
+		return err
+	}
+
+	follows, err := s.client.Follow.FindMany(
+		db.Follow.FollowingID.Equals(j.authorID),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]transaction.Param, 0, len(follows))
+	for _, follow := range follows {
+		entries = append(entries, s.upsertEntry(follow.FollowerID, j.mediaID, j.authorID, j.createdAt))
+	}
+
+	return s.write(ctx, entries)
+}
+
+func (s *Service) backfillFollower(ctx context.Context, j job) error {
+	onRead, err := s.isFanoutOnRead(ctx, j.authorID)
+	if err != nil || onRead {
+		return err
+	}
+
+	medias, err := s.client.Media.FindMany(
+		db.Media.UserID.Equals(j.authorID),
+	).OrderBy(
+		db.Media.CreatedAt.Order(db.DESC),
+	).Take(s.backfill).Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]transaction.Param, 0, len(medias))
+	for _, media := range medias {
+		entries = append(entries, s.upsertEntry(j.followerID, media.ID, j.authorID, media.CreatedAt))
+	}
+
+	return s.write(ctx, entries)
+}
+
+func (s *Service) upsertEntry(userID, mediaID, authorID string, createdAt time.Time) transaction.Param {
+	return s.client.TimelineEntry.UpsertOne(
+		db.TimelineEntry.UserIDMediaID(
+			db.TimelineEntry.UserID.Equals(userID),
+			db.TimelineEntry.MediaID.Equals(mediaID),
+		),
+	).Create(
+		db.TimelineEntry.User.Link(
+			db.User.ID.Equals(userID),
+		),
+		db.TimelineEntry.Media.Link(
+			db.Media.ID.Equals(mediaID),
+		),
+		db.TimelineEntry.AuthorID.Set(authorID),
+		db.TimelineEntry.CreatedAt.Set(createdAt),
+	).Update().Tx()
+}
+
+// write applies entries in batches so one huge fan-out does not turn into a
+// single oversized transaction.
+func (s *Service) write(ctx context.Context, entries []transaction.Param) error {
+	for start := 0; start < len(entries); start += writeBatchSize {
+		end := start + writeBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+
+		if err := s.client.Prisma.Transaction(entries[start:end]...).Exec(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Page returns userID's following timeline, newest first. Materialised
+// entries are merged with uploads of followed creators that are too big to
+// fan out on write.
+func (s *Service) Page(ctx context.Context, userID, cursorValue string, limit int) (Page, error) {
+	var after *cursor
+	if cursorValue != "" {
+		c, err := decodeCursor(cursorValue)
+		if err != nil {
+			return Page{}, err
+		}
+		after = &c
+	}
+
+	limit = clampLimit(limit)
+
+	entryFilter := []db.TimelineEntryWhereParam{db.TimelineEntry.UserID.Equals(userID)}
+	if after != nil {
+		at := time.UnixMilli(after.CreatedAt)
+		entryFilter = append(entryFilter, db.TimelineEntry.Or(
+			db.TimelineEntry.CreatedAt.Lt(at),
+			db.TimelineEntry.And(
+				db.TimelineEntry.CreatedAt.Equals(at),
+				db.TimelineEntry.MediaID.Lt(after.MediaID),
+			),
+		))
+	}
+
+	stored, err := s.client.TimelineEntry.FindMany(
+		entryFilter...,
+	).OrderBy(
+		db.TimelineEntry.CreatedAt.Order(db.DESC),
+		db.TimelineEntry.MediaID.Order(db.DESC),
+	).Take(limit + 1).Exec(ctx)
+	if err != nil {
+		return Page{}, err
+	}
+
+	entries := make([]Entry, 0, len(stored))
+	for _, entry := range stored {
+		entries = append(entries, Entry{MediaID: entry.MediaID, AuthorID: entry.AuthorID, CreatedAt: entry.CreatedAt})
+	}
+
+	pulled, err := s.pull(ctx, userID, after, limit+1)
+	if err != nil {
+		return Page{}, err
+	}
+
+	return paginate(merge(entries, pulled), limit), nil
+}
+
+func clampLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultLimit
+	}
+	if limit > MaxLimit {
+		return MaxLimit
+	}
+	return limit
+}
+
+// paginate cuts merged entries down to one page. Both sources are read with
+// one entry to spare, so a longer list means there is a next page.
+func paginate(entries []Entry, limit int) Page {
+	page := Page{Entries: entries}
+	if len(entries) > limit {
+		page.Entries = entries[:limit]
+		last := page.Entries[limit-1]
+		page.NextCursor = encodeCursor(cursor{CreatedAt: last.CreatedAt.UnixMilli(), MediaID: last.MediaID})
+	}
+
+	return page
+}
+
+// pull reads uploads of followed creators whose timelines are assembled on
+// read instead of on write.
+func (s *Service) pull(ctx context.Context, userID string, after *cursor, limit int) ([]Entry, error) {
+	follows, err := s.client.Follow.FindMany(
+		db.Follow.FollowerID.Equals(userID),
+		db.Follow.Following.Where(
+			db.User.FollowerCount.Gt(s.fanoutLimit),
+		),
+	).Exec(ctx)
+	if err != nil || len(follows) == 0 {
+		return nil, err
+	}
+
+	authorIDs := make([]string, len(follows))
+	for i, follow := range follows {
+		authorIDs[i] = follow.FollowingID
+	}
+
+	mediaFilter := []db.MediaWhereParam{db.Media.UserID.In(authorIDs)}
+	if after != nil {
+		at := time.UnixMilli(after.CreatedAt)
+		mediaFilter = append(mediaFilter, db.Media.Or(
+			db.Media.CreatedAt.Lt(at),
+			db.Media.And(
+				db.Media.CreatedAt.Equals(at),
+				db.Media.ID.Lt(after.MediaID),
+			),
+		))
+	}
+
+	medias, err := s.client.Media.FindMany(
+		mediaFilter...,
+	).OrderBy(
+		db.Media.CreatedAt.Order(db.DESC),
+		db.Media.ID.Order(db.DESC),
+	).Take(limit).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]Entry, len(medias))
+	for i, media := range medias {
+		entries[i] = Entry{MediaID: media.ID, AuthorID: media.UserID, CreatedAt: media.CreatedAt}
+	}
+
+	return entries, nil
+}
+
+// merge combines both sources newest first. An upload can show up in both
+// when its creator crossed the fan-out limit after it was published.
+func merge(a, b []Entry) []Entry {
+	seen := make(map[string]bool, len(a)+len(b))
+	merged := make([]Entry, 0, len(a)+len(b))
+
+	for _, entry := range append(a, b...) {
+		if seen[entry.MediaID] {
+			continue
+		}
+		seen[entry.MediaID] = true
+		merged = append(merged, entry)
+	}
+
+	sort.Slice(merged, func(i, j int) bool {
+		ti, tj := merged[i].CreatedAt.UnixMilli(), merged[j].CreatedAt.UnixMilli()
+		if ti != tj {
+			return ti > tj
+		}
+		return merged[i].MediaID > merged[j].MediaID
+	})
+
+	return merged
+}
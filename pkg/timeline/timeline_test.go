@@ -0,0 +1,186 @@
+package timeline
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func entry(mediaID string, age time.Duration) Entry {
+	return Entry{MediaID: mediaID, AuthorID: "author-" + mediaID, CreatedAt: testNow.Add(-age)}
+}
+
+func mediaIDs(entries []Entry) []string {
+	ids := make([]string, len(entries))
+	for i, entry := range entries {
+		ids[i] = entry.MediaID
+	}
+	return ids
+}
+
+func TestMerge(t *testing.T) {
+	tests := []struct {
+		name   string
+		stored []Entry
+		pulled []Entry
+		want   []string
+	}{
+		{
+			name: "both empty",
+			want: []string{},
+		},
+		{
+			name:   "only stored",
+			stored: []Entry{entry("a", time.Minute), entry("b", time.Hour)},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "only pulled",
+			pulled: []Entry{entry("a", time.Minute), entry("b", time.Hour)},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "interleaved newest first",
+			stored: []Entry{entry("a", time.Minute), entry("c", 3*time.Minute)},
+			pulled: []Entry{entry("b", 2*time.Minute), entry("d", 4*time.Minute)},
+			want:   []string{"a", "b", "c", "d"},
+		},
+		{
+			name:   "upload in both sources",
+			stored: []Entry{entry("a", time.Minute), entry("b", 2*time.Minute)},
+			pulled: []Entry{entry("b", 2*time.Minute)},
+			want:   []string{"a", "b"},
+		},
+		{
+			name:   "same millisecond ordered by id",
+			stored: []Entry{entry("a", time.Minute)},
+			pulled: []Entry{entry("c", time.Minute), entry("b", time.Minute-time.Microsecond)},
+			want:   []string{"c", "b", "a"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mediaIDs(merge(tt.stored, tt.pulled))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("merge() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClampLimit(t *testing.T) {
+	tests := []struct {
+		name  string
+		limit int
+		want  int
+	}{
+		{name: "missing", limit: 0, want: DefaultLimit},
+		{name: "negative", limit: -5, want: DefaultLimit},
+		{name: "in range", limit: 10, want: 10},
+		{name: "maximum", limit: MaxLimit, want: MaxLimit},
+		{name: "too large", limit: MaxLimit + 1, want: MaxLimit},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := clampLimit(tt.limit); got != tt.want {
+				t.Errorf("clampLimit(%d) = %d, want %d", tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	entries := []Entry{
+		entry("d", time.Minute),
+		entry("c", 2*time.Minute),
+		entry("b", 3*time.Minute),
+		entry("a", 4*time.Minute),
+	}
+
+	tests := []struct {
+		name       string
+		entries    []Entry
+		limit      int
+		want       []string
+		wantCursor *cursor
+	}{
+		{
+			name:  "empty",
+			limit: 2,
+			want:  []string{},
+		},
+		{
+			name:    "shorter than the limit",
+			entries: entries[:1],
+			limit:   2,
+			want:    []string{"d"},
+		},
+		{
+			name:    "exactly the limit",
+			entries: entries[:2],
+			limit:   2,
+			want:    []string{"d", "c"},
+		},
+		{
+			name:       "more than the limit",
+			entries:    entries,
+			limit:      2,
+			want:       []string{"d", "c"},
+			wantCursor: &cursor{CreatedAt: entries[1].CreatedAt.UnixMilli(), MediaID: "c"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page := paginate(tt.entries, tt.limit)
+
+			if got := mediaIDs(page.Entries); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("paginate() entries = %v, want %v", got, tt.want)
+			}
+
+			if tt.wantCursor == nil {
+				if page.NextCursor != "" {
+					t.Errorf("paginate() cursor = %q, want none", page.NextCursor)
+				}
+				return
+			}
+
+			got, err := decodeCursor(page.NextCursor)
+			if err != nil || got != *tt.wantCursor {
+				t.Errorf("paginate() cursor = %+v, %v, want %+v", got, err, *tt.wantCursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursor(t *testing.T) {
+	valid := cursor{CreatedAt: testNow.UnixMilli(), MediaID: "m1"}
+
+	tests := []struct {
+		name    string
+		value   string
+		want    cursor
+		wantErr error
+	}{
+		{name: "round trip", value: encodeCursor(valid), want: valid},
+		{name: "not base64", value: "***", wantErr: ErrInvalidCursor},
+		{name: "not json", value: "bm90IGpzb24", wantErr: ErrInvalidCursor},
+		{name: "missing media id", value: encodeCursor(cursor{CreatedAt: 1}), wantErr: ErrInvalidCursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := decodeCursor(tt.value)
+			if err != tt.wantErr {
+				t.Fatalf("decodeCursor(%q) error = %v, want %v", tt.value, err, tt.wantErr)
+			}
+			if err == nil && got != tt.want {
+				t.Errorf("decodeCursor(%q) = %+v, want %+v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package tracing
+
+import (
+	"context"
+	"vilow-be/pkg/metrics"
+
+	"github.com/steebchen/prisma-client-go/engine"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.24.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// InstrumentEngine wraps the query engine of a Prisma client so every query
+// gets a client span under the span of the request that sent it.
+func InstrumentEngine(next engine.Engine) engine.Engine {
+	return &tracedEngine{Engine: next}
+}
+
+type tracedEngine struct {
+	engine.Engine
+}
+
+func (e *tracedEngine) Do(ctx context.Context, payload interface{}, into interface{}) error {
+	return traceQuery(ctx, metrics.OperationName(payload), func(ctx context.Context) error {
+		return e.Engine.Do(ctx, payload, into)
+	})
+}
+
+func (e *tracedEngine) Batch(ctx context.Context, payload interface{}, into interface{}) error {
+	return traceQuery(ctx, "batch", func(ctx context.Context) error {
+		return e.Engine.Batch(ctx, payload, into)
+	})
+}
+
+func traceQuery(ctx context.Context, operation string, query func(context.Context) error) error {
+	ctx, span := Tracer().Start(ctx, "prisma "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemMongoDB, semconv.DBOperation(operation)),
+	)
+	defer span.End()
+
+	err := query(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	return err
+}
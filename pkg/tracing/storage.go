@@ -0,0 +1,20 @@
+package tracing
+
+import (
+	"net/http"
+
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// InstrumentTransport wraps the HTTP transport of the MinIO client so every
+// S3 request gets a client span. Trace headers are not sent along, MinIO has
+// no use for them.
+func InstrumentTransport(next http.RoundTripper) http.RoundTripper {
+	return otelhttp.NewTransport(next,
+		otelhttp.WithPropagators(propagation.NewCompositeTextMapPropagator()),
+		otelhttp.WithSpanNameFormatter(func(_ string, r *http.Request) string {
+			return "minio " + r.Method
+		}),
+	)
+}
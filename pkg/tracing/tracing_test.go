@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/steebchen/prisma-client-go/engine"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestValidExporter(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{name: ExporterNone, want: true},
+		{name: ExporterStdout, want: true},
+		{name: ExporterFile, want: true},
+		{name: ExporterOTLP, want: true},
+		{name: "jaeger", want: false},
+		{name: "", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidExporter(tt.name); got != tt.want {
+				t.Errorf("ValidExporter(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetup(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+
+	file := filepath.Join(t.TempDir(), "traces.json")
+
+	tests := []struct {
+		name    string
+		options Options
+		wantErr bool
+	}{
+		{name: "disabled", options: Options{Exporter: ExporterNone}},
+		{name: "file", options: Options{Exporter: ExporterFile, File: file, ServiceName: "vilow-test", SampleRatio: 1}},
+		{name: "unwritable file", options: Options{Exporter: ExporterFile, File: filepath.Join(file, "nested")}, wantErr: true},
+		{name: "unknown exporter", options: Options{Exporter: "jaeger"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Setup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			_, span := Tracer().Start(context.Background(), "setup test")
+			span.End()
+
+			if err := shutdown(context.Background()); err != nil {
+				t.Errorf("shutdown() error = %v", err)
+			}
+		})
+	}
+
+	// Shutting down flushes the span of the file exporter.
+	data, err := os.ReadFile(file)
+	if err != nil || !strings.Contains(string(data), `"Name":"setup test"`) || !strings.Contains(string(data), "vilow-test") {
+		t.Errorf("trace file = %s, %v, want the span of the file exporter", data, err)
+	}
+}
+
+type fakeEngine struct {
+	engine.Engine
+	err error
+}
+
+func (e fakeEngine) Do(ctx context.Context, payload interface{}, into interface{}) error {
+	return e.err
+}
+
+func (e fakeEngine) Batch(ctx context.Context, payload interface{}, into interface{}) error {
+	return e.err
+}
+
+func TestInstrumentEngine(t *testing.T) {
+	defer otel.SetTracerProvider(otel.GetTracerProvider())
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	payload := engine.GQLRequest{Query: "query {result: findUniqueUser(where: {}) {id}}"}
+
+	tests := []struct {
+		name       string
+		batch      bool
+		err        error
+		wantName   string
+		wantStatus codes.Code
+	}{
+		{name: "query", wantName: "prisma findUniqueUser", wantStatus: codes.Unset},
+		{name: "batch", batch: true, wantName: "prisma batch", wantStatus: codes.Unset},
+		{name: "failed query", err: errors.New("timed out"), wantName: "prisma findUniqueUser", wantStatus: codes.Error},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instrumented := InstrumentEngine(fakeEngine{err: tt.err})
+			parent, parentSpan := Tracer().Start(context.Background(), "request")
+
+			var err error
+			if tt.batch {
+				err = instrumented.Batch(parent, payload, nil)
+			} else {
+				err = instrumented.Do(parent, payload, nil)
+			}
+			parentSpan.End()
+
+			if err != tt.err {
+				t.Errorf("error = %v, want %v", err, tt.err)
+			}
+
+			ended := recorder.Ended()
+			span := ended[len(ended)-2]
+			if span.Name() != tt.wantName || span.Status().Code != tt.wantStatus {
+				t.Errorf("span = %q, %v, want %q, %v", span.Name(), span.Status().Code, tt.wantName, tt.wantStatus)
+			}
+			if span.SpanKind() != trace.SpanKindClient || span.Parent().SpanID() != parentSpan.SpanContext().SpanID() {
+				t.Errorf("span is not a client span under the request span")
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package trending
+
+import (
+	"context"
+	"sync"
+	"time"
+	"vilow-be/pkg/logging"
+	"vilow-be/prisma/db"
+)
+
+// Aggregator keeps a periodically refreshed Snapshot, so the explore and
+// trending endpoints never scan the collections themselves.
+type Aggregator struct {
+	client   *db.PrismaClient
+	weights  Weights
+	interval time.Duration
+
+	mu       sync.RWMutex
+	snapshot Snapshot
+}
+
+func NewAggregator(client *db.PrismaClient, weights Weights, interval time.Duration) *Aggregator {
+	return &Aggregator{
+		client:   client,
+		weights:  weights,
+		interval: interval,
+		snapshot: Compute(nil, nil, weights, time.Time{}),
+	}
+}
+
+// Run refreshes the snapshot right away and then every interval until ctx
+// is cancelled.
+func (a *Aggregator) Run(ctx context.Context) {
+	ticker := time.NewTicker(a.interval)
+	defer ticker.Stop()
+
+	for {
+		if err := a.Refresh(ctx); err != nil {
+			logging.FromContext(ctx).Error("Error refreshing trending snapshot", "error", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Snapshot returns the latest aggregated view. Before the first refresh it
+// is empty with a zero GeneratedAt.
+func (a *Aggregator) Snapshot() Snapshot {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	return a.snapshot
+}
+
+// Refresh recomputes the snapshot from the database.
+func (a *Aggregator) Refresh(ctx context.Context) error {
+	now := time.Now()
+
+	medias, err := a.client.Media.FindMany(
+		db.Media.Hidden.Equals(false),
+	).With(
+		db.Media.Likes.Fetch(),
+		db.Media.Dislikes.Fetch(),
+		db.Media.Comments.Fetch(),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	users, err := a.client.User.FindMany(
+		db.User.Hidden.Equals(false),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	stats := make([]Media, len(medias))
+	for i, media := range medias {
+		stats[i] = a.mediaStats(&media, now)
+	}
+
+	userSubjects := make([][]string, len(users))
+	for i, user := range users {
+		userSubjects[i] = user.Subjects
+	}
+
+	snapshot := Compute(stats, userSubjects, a.weights, now)
+
+	a.mu.Lock()
+	a.snapshot = snapshot
+	a.mu.Unlock()
+
+	return nil
+}
+
+func (a *Aggregator) mediaStats(media *db.MediaModel, now time.Time) Media {
+	relations := media.RelationsMedia
+	stats := Media{
+		ID:        media.ID,
+		Subjects:  media.Subjects,
+		Likes:     len(relations.Likes),
+		Dislikes:  len(relations.Dislikes),
+		Comments:  len(relations.Comments),
+		CreatedAt: media.CreatedAt,
+	}
+
+	since := now.Add(-a.weights.Window)
+	recent := func(kind EventKind, at time.Time) {
+		if !at.Before(since) {
+			stats.Recent = append(stats.Recent, Event{Kind: kind, At: at})
+		}
+	}
+
+	for _, like := range relations.Likes {
+		recent(Like, like.CreatedAt)
+	}
+	for _, dislike := range relations.Dislikes {
+		recent(Dislike, dislike.CreatedAt)
+	}
+	for _, comment := range relations.Comments {
+		recent(Comment, comment.CreatedAt)
+	}
+
+	return stats
+}
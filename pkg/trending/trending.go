@@ -0,0 +1,239 @@
+package trending
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"vilow-be/pkg/feed"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 50
+
+	// DefaultRefreshInterval is how often the aggregator rebuilds its snapshot.
+	DefaultRefreshInterval = 5 * time.Minute
+)
+
+type EventKind int
+
+const (
+	Like EventKind = iota
+	Dislike
+	Comment
+)
+
+// Event is a single reaction to a media item.
+type Event struct {
+	Kind EventKind
+	At   time.Time
+}
+
+// Media is a media item with its lifetime engagement totals and the events
+// that happened inside the trending window.
+type Media struct {
+	ID        string
+	Subjects  []string
+	Likes     int
+	Dislikes  int
+	Comments  int
+	Recent    []Event
+	CreatedAt time.Time
+}
+
+// Weights controls how much every kind of reaction adds to the velocity
+// and how quickly it fades.
+type Weights struct {
+	Like     float64
+	Dislike  float64
+	Comment  float64
+	Window   time.Duration
+	HalfLife time.Duration
+}
+
+var DefaultWeights = Weights{
+	Like:     1,
+	Dislike:  -0.5,
+	Comment:  2,
+	Window:   24 * time.Hour,
+	HalfLife: 6 * time.Hour,
+}
+
+type Subject struct {
+	Name   string
+	Medias int
+	Users  int
+}
+
+type Item struct {
+	ID    string
+	Score float64
+}
+
+// Snapshot is the aggregated view served by the explore and trending
+// endpoints. BySubject is keyed by normalised subject.
+type Snapshot struct {
+	Subjects    []Subject
+	Trending    []Item
+	BySubject   map[string][]Item
+	GeneratedAt time.Time
+}
+
+// NormalizeSubject folds the spelling variants users type into one key.
+func NormalizeSubject(subject string) string {
+	return strings.ToLower(strings.TrimSpace(subject))
+}
+
+// Velocity sums the reactions inside the window, each one decaying with its
+// age, so a burst in the last hour beats the same amount spread over a day.
+func (w Weights) Velocity(events []Event, now time.Time) float64 {
+	velocity := 0.0
+
+	for _, event := range events {
+		age := now.Sub(event.At)
+		if age < 0 {
+			age = 0
+		}
+		if age > w.Window {
+			continue
+		}
+
+		weight := w.Like
+		switch event.Kind {
+		case Dislike:
+			weight = w.Dislike
+		case Comment:
+			weight = w.Comment
+		}
+
+		if w.HalfLife > 0 {
+			weight *= math.Pow(0.5, float64(age)/float64(w.HalfLife))
+		}
+		velocity += weight
+	}
+
+	return velocity
+}
+
+// Quality ranks media inside a subject by lifetime approval and discussion.
+func Quality(media Media) float64 {
+	comments := math.Log1p(float64(media.Comments))
+	return feed.WilsonLowerBound(media.Likes, media.Likes+media.Dislikes) + comments/(1+comments)
+}
+
+// Compute builds a snapshot from every media item and the subjects of every
+// user. Lists are capped at MaxLimit since nothing reads past that.
+func Compute(medias []Media, userSubjects [][]string, weights Weights, now time.Time) Snapshot {
+	snapshot := Snapshot{
+		Subjects:    []Subject{},
+		Trending:    []Item{},
+		BySubject:   make(map[string][]Item),
+		GeneratedAt: now,
+	}
+
+	subjects := make(map[string]*Subject)
+	subject := func(name string) *Subject {
+		if subjects[name] == nil {
+			subjects[name] = &Subject{Name: name}
+		}
+		return subjects[name]
+	}
+
+	for _, media := range medias {
+		for _, name := range distinct(media.Subjects) {
+			subject(name).Medias++
+			snapshot.BySubject[name] = append(snapshot.BySubject[name], Item{ID: media.ID, Score: Quality(media)})
+		}
+
+		if velocity := weights.Velocity(media.Recent, now); velocity > 0 {
+			snapshot.Trending = append(snapshot.Trending, Item{ID: media.ID, Score: velocity})
+		}
+	}
+
+	for _, names := range userSubjects {
+		for _, name := range distinct(names) {
+			subject(name).Users++
+		}
+	}
+
+	for _, s := range subjects {
+		snapshot.Subjects = append(snapshot.Subjects, *s)
+	}
+	sort.Slice(snapshot.Subjects, func(i, j int) bool {
+		a, b := snapshot.Subjects[i], snapshot.Subjects[j]
+		if a.Medias != b.Medias {
+			return a.Medias > b.Medias
+		}
+		if a.Users != b.Users {
+			return a.Users > b.Users
+		}
+		return a.Name < b.Name
+	})
+
+	snapshot.Trending = top(snapshot.Trending)
+	for name, items := range snapshot.BySubject {
+		snapshot.BySubject[name] = top(items)
+	}
+
+	return snapshot
+}
+
+// TopSubjects returns the most used subjects.
+func (s Snapshot) TopSubjects(limit int) []Subject {
+	return s.Subjects[:clamp(limit, len(s.Subjects))]
+}
+
+// TopTrending returns the media with the highest engagement velocity.
+func (s Snapshot) TopTrending(limit int) []Item {
+	return s.Trending[:clamp(limit, len(s.Trending))]
+}
+
+// Best returns the highest quality media tagged with subject.
+func (s Snapshot) Best(subject string, limit int) []Item {
+	items := s.BySubject[NormalizeSubject(subject)]
+	return items[:clamp(limit, len(items))]
+}
+
+func distinct(subjects []string) []string {
+	seen := make(map[string]bool, len(subjects))
+	names := make([]string, 0, len(subjects))
+
+	for _, subject := range subjects {
+		name := NormalizeSubject(subject)
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		names = append(names, name)
+	}
+
+	return names
+}
+
+func top(items []Item) []Item {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Score != items[j].Score {
+			return items[i].Score > items[j].Score
+		}
+		return items[i].ID < items[j].ID
+	})
+
+	if len(items) > MaxLimit {
+		items = items[:MaxLimit]
+	}
+	return items
+}
+
+func clamp(limit, available int) int {
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+	if limit > available {
+		limit = available
+	}
+	return limit
+}
@@ -0,0 +1,108 @@
+package trending
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func TestVelocity(t *testing.T) {
+	weights := Weights{Like: 1, Dislike: -0.5, Comment: 2, Window: 24 * time.Hour, HalfLife: 6 * time.Hour}
+
+	tests := []struct {
+		name   string
+		events []Event
+		want   float64
+	}{
+		{name: "no events", events: nil, want: 0},
+		{name: "fresh like", events: []Event{{Kind: Like, At: testNow}}, want: 1},
+		{name: "fresh comment", events: []Event{{Kind: Comment, At: testNow}}, want: 2},
+		{name: "fresh dislike", events: []Event{{Kind: Dislike, At: testNow}}, want: -0.5},
+		{name: "like one half-life old", events: []Event{{Kind: Like, At: testNow.Add(-6 * time.Hour)}}, want: 0.5},
+		{name: "outside the window", events: []Event{{Kind: Like, At: testNow.Add(-25 * time.Hour)}}, want: 0},
+		{name: "in the future counts as now", events: []Event{{Kind: Like, At: testNow.Add(time.Hour)}}, want: 1},
+		{
+			name: "mixed",
+			events: []Event{
+				{Kind: Like, At: testNow},
+				{Kind: Comment, At: testNow.Add(-12 * time.Hour)},
+				{Kind: Dislike, At: testNow},
+			},
+			want: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := weights.Velocity(tt.events, testNow)
+			if math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Velocity() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompute(t *testing.T) {
+	medias := []Media{
+		{ID: "a", Subjects: []string{"Go", " go ", "Music"}, Likes: 10, Recent: []Event{{Kind: Like, At: testNow}}},
+		{ID: "b", Subjects: []string{"go"}, Likes: 100, Dislikes: 5, Comments: 20, Recent: []Event{{Kind: Comment, At: testNow}}},
+		{ID: "c", Subjects: []string{"cooking", ""}, Recent: []Event{{Kind: Dislike, At: testNow}}},
+	}
+	users := [][]string{{"Music", "music"}, {"cooking"}, {"music"}}
+
+	snapshot := Compute(medias, users, DefaultWeights, testNow)
+
+	wantSubjects := []Subject{
+		{Name: "go", Medias: 2, Users: 0},
+		{Name: "music", Medias: 1, Users: 2},
+		{Name: "cooking", Medias: 1, Users: 1},
+	}
+	if !reflect.DeepEqual(snapshot.Subjects, wantSubjects) {
+		t.Errorf("Subjects = %+v, want %+v", snapshot.Subjects, wantSubjects)
+	}
+
+	tests := []struct {
+		name string
+		got  []Item
+		want []string
+	}{
+		{name: "trending leaves out negative velocity", got: snapshot.TopTrending(0), want: []string{"b", "a"}},
+		{name: "best of a subject by quality", got: snapshot.Best(" GO", 0), want: []string{"b", "a"}},
+		{name: "best of an unknown subject", got: snapshot.Best("chess", 0), want: []string{}},
+		{name: "limit", got: snapshot.Best("go", 1), want: []string{"b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ids := []string{}
+			for _, item := range tt.got {
+				ids = append(ids, item.ID)
+			}
+			if !reflect.DeepEqual(ids, tt.want) {
+				t.Errorf("items = %v, want %v", ids, tt.want)
+			}
+		})
+	}
+}
+
+func TestClamp(t *testing.T) {
+	tests := []struct {
+		limit, available, want int
+	}{
+		{limit: 0, available: 100, want: DefaultLimit},
+		{limit: -1, available: 100, want: DefaultLimit},
+		{limit: 5, available: 100, want: 5},
+		{limit: 500, available: 100, want: MaxLimit},
+		{limit: 10, available: 3, want: 3},
+		{limit: 10, available: 0, want: 0},
+	}
+
+	for _, tt := range tests {
+		if got := clamp(tt.limit, tt.available); got != tt.want {
+			t.Errorf("clamp(%d, %d) = %d, want %d", tt.limit, tt.available, got, tt.want)
+		}
+	}
+}
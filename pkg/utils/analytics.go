@@ -0,0 +1,144 @@
+package utils
+
+import (
+	"context"
+	"time"
+	"vilow-be/pkg/analytics"
+	"vilow-be/pkg/dto"
+	"vilow-be/prisma/db"
+)
+
+// LoadAnalytics fills report with the numbers of medias. Follower gains are
+// only added when creatorID is set, since they belong to a channel rather
+// than a single upload.
+func LoadAnalytics(ctx context.Context, client *db.PrismaClient, report *analytics.Report, medias []db.MediaModel, creatorID string) error {
+	from, end := report.From, report.End()
+
+	mediaIDs := make([]string, len(medias))
+	durations := make(map[string]float64, len(medias))
+	for i, media := range medias {
+		mediaIDs[i] = media.ID
+		durations[media.ID] = media.Duration
+	}
+
+	stats, err := client.MediaDailyStat.FindMany(
+		db.MediaDailyStat.MediaID.In(mediaIDs),
+		db.MediaDailyStat.Day.Gte(from),
+		db.MediaDailyStat.Day.Lt(end),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	for _, stat := range stats {
+		report.AddPlayback(stat.Day, stat.Views, stat.WatchTime, durations[stat.MediaID])
+	}
+
+	viewers, err := client.MediaDailyViewer.FindMany(
+		db.MediaDailyViewer.MediaID.In(mediaIDs),
+		db.MediaDailyViewer.Day.Gte(from),
+		db.MediaDailyViewer.Day.Lt(end),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	// Viewers are recorded per media, someone who watched several of the
+	// medias on a day still counts once.
+	type dayViewer struct {
+		userID string
+		day    time.Time
+	}
+	seen := make(map[dayViewer]bool, len(viewers))
+	for _, viewer := range viewers {
+		key := dayViewer{userID: viewer.UserID, day: viewer.Day.UTC()}
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+		report.AddViewer(viewer.Day)
+	}
+
+	likes, err := client.Like.FindMany(
+		db.Like.MediaID.In(mediaIDs),
+		db.Like.CreatedAt.Gte(from),
+		db.Like.CreatedAt.Lt(end),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	for _, like := range likes {
+		report.AddLike(like.CreatedAt)
+	}
+
+	dislikes, err := client.Dislike.FindMany(
+		db.Dislike.MediaID.In(mediaIDs),
+		db.Dislike.CreatedAt.Gte(from),
+		db.Dislike.CreatedAt.Lt(end),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	for _, dislike := range dislikes {
+		report.AddDislike(dislike.CreatedAt)
+	}
+
+	comments, err := client.Comment.FindMany(
+		db.Comment.MediaID.In(mediaIDs),
+		db.Comment.CreatedAt.Gte(from),
+		db.Comment.CreatedAt.Lt(end),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		report.AddComment(comment.CreatedAt)
+	}
+
+	if creatorID != "" {
+		follows, err := client.Follow.FindMany(
+			db.Follow.FollowingID.Equals(creatorID),
+			db.Follow.CreatedAt.Gte(from),
+			db.Follow.CreatedAt.Lt(end),
+		).Exec(ctx)
+		if err != nil {
+			return err
+		}
+		for _, follow := range follows {
+			report.AddFollower(follow.CreatedAt)
+		}
+	}
+
+	report.Finish()
+	return nil
+}
+
+func BuildAnalyticsResponse(report *analytics.Report) dto.AnalyticsResponse {
+	response := dto.AnalyticsResponse{
+		From:   report.From.Format(time.DateOnly),
+		To:     report.To.Format(time.DateOnly),
+		Totals: buildAnalyticsDay(report.Totals),
+		Days:   make([]dto.AnalyticsDay, len(report.Days)),
+	}
+
+	// Totals span the whole range, so they carry no date of their own.
+	response.Totals.Date = ""
+
+	for i, day := range report.Days {
+		response.Days[i] = buildAnalyticsDay(day)
+	}
+
+	return response
+}
+
+func buildAnalyticsDay(day analytics.Day) dto.AnalyticsDay {
+	return dto.AnalyticsDay{
+		Date:                   day.Date.Format(time.DateOnly),
+		Views:                  day.Views,
+		UniqueViewers:          day.UniqueViewers,
+		WatchTime:              day.WatchTime,
+		AverageWatchPercentage: day.AverageWatchPercentage,
+		Likes:                  day.Likes,
+		Dislikes:               day.Dislikes,
+		Comments:               day.Comments,
+		FollowerGains:          day.FollowerGains,
+	}
+}
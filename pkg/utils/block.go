@@ -0,0 +1,117 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"vilow-be/prisma/db"
+)
+
+// IsBlocked reports whether either user blocked the other. Blocks cut all
+// interaction both ways, so the direction does not matter here.
+func IsBlocked(ctx context.Context, client *db.PrismaClient, userID, otherID string) (bool, error) {
+	blocks, err := client.Block.FindMany(
+		db.Block.Or(
+			db.Block.And(
+				db.Block.BlockerID.Equals(userID),
+				db.Block.BlockedID.Equals(otherID),
+			),
+			db.Block.And(
+				db.Block.BlockerID.Equals(otherID),
+				db.Block.BlockedID.Equals(userID),
+			),
+		),
+	).Take(1).Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return len(blocks) > 0, nil
+}
+
+// HasBlocked reports whether blockerID blocked blockedID.
+func HasBlocked(ctx context.Context, client *db.PrismaClient, blockerID, blockedID string) (bool, error) {
+	_, err := client.Block.FindUnique(
+		db.Block.BlockerIDBlockedID(
+			db.Block.BlockerID.Equals(blockerID),
+			db.Block.BlockedID.Equals(blockedID),
+		),
+	).Exec(ctx)
+
+	if errors.Is(err, db.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// BlockerIDs returns the users who blocked userID. Their media and profiles
+// are not shown to userID.
+func BlockerIDs(ctx context.Context, client *db.PrismaClient, userID string) ([]string, error) {
+	blocks, err := client.Block.FindMany(
+		db.Block.BlockedID.Equals(userID),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(blocks))
+	for i, block := range blocks {
+		ids[i] = block.BlockerID
+	}
+
+	return ids, nil
+}
+
+// BlockedIDs returns the users userID blocked.
+func BlockedIDs(ctx context.Context, client *db.PrismaClient, userID string) ([]string, error) {
+	blocks, err := client.Block.FindMany(
+		db.Block.BlockerID.Equals(userID),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, len(blocks))
+	for i, block := range blocks {
+		ids[i] = block.BlockedID
+	}
+
+	return ids, nil
+}
+
+// FeedExclusions returns the creators whose media stay out of userID's
+// feeds: everyone blocked in either direction and everyone userID muted.
+func FeedExclusions(ctx context.Context, client *db.PrismaClient, userID string) ([]string, error) {
+	blocks, err := client.Block.FindMany(
+		db.Block.Or(
+			db.Block.BlockerID.Equals(userID),
+			db.Block.BlockedID.Equals(userID),
+		),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	mutes, err := client.Mute.FindMany(
+		db.Mute.MuterID.Equals(userID),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]string, 0, len(blocks)+len(mutes))
+	for _, block := range blocks {
+		if block.BlockerID == userID {
+			ids = append(ids, block.BlockedID)
+		} else {
+			ids = append(ids, block.BlockerID)
+		}
+	}
+	for _, mute := range mutes {
+		ids = append(ids, mute.MutedID)
+	}
+
+	return ids, nil
+}
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"context"
+	"vilow-be/pkg/dto"
+	"vilow-be/prisma/db"
+)
+
+// BookmarkedMediaIDs returns which of mediaIDs userID bookmarked.
+func BookmarkedMediaIDs(ctx context.Context, client *db.PrismaClient, userID string, mediaIDs []string) (map[string]bool, error) {
+	bookmarked := make(map[string]bool)
+	if len(mediaIDs) == 0 {
+		return bookmarked, nil
+	}
+
+	bookmarks, err := client.Bookmark.FindMany(
+		db.Bookmark.UserID.Equals(userID),
+		db.Bookmark.MediaID.In(mediaIDs),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, bookmark := range bookmarks {
+		bookmarked[bookmark.MediaID] = true
+	}
+
+	return bookmarked, nil
+}
+
+// MarkBookmarked sets IsBookmarked on the medias userID bookmarked.
+func MarkBookmarked(ctx context.Context, client *db.PrismaClient, userID string, medias []dto.Media) error {
+	mediaIDs := make([]string, len(medias))
+	for i, media := range medias {
+		mediaIDs[i] = media.ID
+	}
+
+	bookmarked, err := BookmarkedMediaIDs(ctx, client, userID, mediaIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range medias {
+		medias[i].IsBookmarked = bookmarked[medias[i].ID]
+	}
+
+	return nil
+}
+
+// BuildViewerMedias maps media records to their response shape, marking the
+// ones userID bookmarked.
+func BuildViewerMedias(ctx context.Context, client *db.PrismaClient, userID string, medias []db.MediaModel) ([]dto.Media, error) {
+	mediaIDs := make([]string, len(medias))
+	for i, media := range medias {
+		mediaIDs[i] = media.ID
+	}
+
+	bookmarked, err := BookmarkedMediaIDs(ctx, client, userID, mediaIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	response := make([]dto.Media, len(medias))
+	for i := range medias {
+		response[i] = BuildMediaResponse(&medias[i])
+		response[i].IsBookmarked = bookmarked[medias[i].ID]
+	}
+
+	return response, nil
+}
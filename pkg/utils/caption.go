@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"vilow-be/pkg/dto"
+	"vilow-be/prisma/db"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// CaptionURLExpiry is how long the link to a caption file stays valid. It
+// only needs to outlast the page that plays the media.
+const CaptionURLExpiry = 6 * time.Hour
+
+// CaptionObjectName is where the WebVTT file of a caption track is stored.
+func CaptionObjectName(mediaID, language string) string {
+	return fmt.Sprintf("captions/%s/%s.vtt", mediaID, language)
+}
+
+// CaptionURL presigns a link to the WebVTT file of caption. Browsers load
+// <track> files without the Authorization header, so they are fetched from
+// MinIO directly, the same way as the video, once the API has checked that
+// the viewer may watch the media.
+func CaptionURL(ctx context.Context, minioClient *minio.Client, bucketName string, caption *db.CaptionModel) (string, error) {
+	url, err := minioClient.PresignedGetObject(ctx, bucketName, caption.ObjectName, CaptionURLExpiry, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return url.String(), nil
+}
+
+func BuildCaptionResponse(caption *db.CaptionModel, url string) dto.Caption {
+	return dto.Caption{
+		Language:  caption.Language,
+		Label:     caption.Label,
+		URL:       url,
+		UpdatedAt: caption.UpdatedAt,
+	}
+}
@@ -0,0 +1,56 @@
+package utils
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"vilow-be/pkg/chapters"
+	"vilow-be/prisma/db"
+)
+
+// ResolveChapters picks the chapters of a media: the explicit list when the
+// creator sent one, the markers of the description otherwise. They are
+// validated against duration, zero when it is not known yet.
+func ResolveChapters(explicit string, hasExplicit bool, description string, duration float64) ([]chapters.Chapter, error) {
+	list := chapters.Parse(description)
+
+	if hasExplicit {
+		list = []chapters.Chapter{}
+		if explicit != "" {
+			if err := json.Unmarshal([]byte(explicit), &list); err != nil {
+				return nil, errors.New("chapters must be a list of {start, title} objects")
+			}
+		}
+	}
+
+	list = chapters.Normalize(list)
+	if err := chapters.Validate(list, duration); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+func EncodeChapters(list []chapters.Chapter) (db.JSON, error) {
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.JSON(raw), nil
+}
+
+// DecodeChapters reads chapters stored by EncodeChapters.
+func DecodeChapters(raw db.JSON) []chapters.Chapter {
+	list := []chapters.Chapter{}
+	if len(raw) == 0 {
+		return list
+	}
+
+	if err := json.Unmarshal(raw, &list); err != nil {
+		slog.Error("Error decoding chapters", "error", err)
+		return []chapters.Chapter{}
+	}
+
+	return list
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"vilow-be/pkg/entities"
+	"vilow-be/pkg/logging"
+	"vilow-be/prisma/db"
+)
+
+// ParseEntities parses the mentions and hashtags of text and resolves the
+// mentions to users. Mentions of unknown or hidden users are dropped.
+func ParseEntities(ctx context.Context, client *db.PrismaClient, text string) ([]entities.Entity, error) {
+	parsed := entities.Parse(text)
+
+	strIDs := entities.Mentions(parsed)
+	if len(strIDs) == 0 {
+		return parsed, nil
+	}
+
+	users, err := client.User.FindMany(
+		db.User.StrID.In(strIDs),
+		db.User.Hidden.Equals(false),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	userIDs := make(map[string]string, len(users))
+	for _, user := range users {
+		userIDs[user.StrID] = user.ID
+	}
+
+	resolved := []entities.Entity{}
+	for _, entity := range parsed {
+		if entity.Kind == entities.KindMention {
+			userID, ok := userIDs[entity.Value]
+			if !ok {
+				continue
+			}
+			entity.UserID = userID
+		}
+		resolved = append(resolved, entity)
+	}
+
+	return resolved, nil
+}
+
+// EncodeEntities converts entities to the JSON stored next to the text.
+func EncodeEntities(list []entities.Entity) (db.JSON, error) {
+	raw, err := json.Marshal(list)
+	if err != nil {
+		return nil, err
+	}
+
+	return db.JSON(raw), nil
+}
+
+// DecodeEntities reads entities stored by EncodeEntities. Records written
+// before entities were stored have none.
+func DecodeEntities(raw db.JSON) []entities.Entity {
+	list := []entities.Entity{}
+	if len(raw) == 0 {
+		return list
+	}
+
+	if err := json.Unmarshal(raw, &list); err != nil {
+		slog.Error("Error decoding entities", "error", err)
+		return []entities.Entity{}
+	}
+
+	return list
+}
+
+// MentionedUserIDs returns the distinct users mentioned in entities.
+func MentionedUserIDs(list []entities.Entity) []string {
+	seen := make(map[string]bool)
+	userIDs := []string{}
+
+	for _, entity := range list {
+		if entity.Kind != entities.KindMention || entity.UserID == "" || seen[entity.UserID] {
+			continue
+		}
+		seen[entity.UserID] = true
+		userIDs = append(userIDs, entity.UserID)
+	}
+
+	return userIDs
+}
+
+// NotifyMentions notifies the users mentioned in entities by actorID.
+// Users in skip, usually those already notified, are left out.
+func NotifyMentions(ctx context.Context, client *db.PrismaClient, actorID string, list []entities.Entity, content string, skip []string) {
+	skipped := make(map[string]bool, len(skip)+1)
+	skipped[actorID] = true
+	for _, userID := range skip {
+		skipped[userID] = true
+	}
+
+	for _, userID := range MentionedUserIDs(list) {
+		if skipped[userID] {
+			continue
+		}
+
+		if err := NotifyFrom(ctx, client, actorID, userID, content); err != nil {
+			logging.FromContext(ctx).Error("Error notifying user", "user_id", userID, "error", err)
+		}
+	}
+}
+
+// UpdateHashtagCounts keeps the hashtag index in line with a media whose
+// description gained the added tags and lost the removed ones.
+func UpdateHashtagCounts(ctx context.Context, client *db.PrismaClient, added, removed []string) error {
+	for _, tag := range added {
+		_, err := client.Hashtag.UpsertOne(
+			db.Hashtag.Tag.Equals(tag),
+		).Create(
+			db.Hashtag.Tag.Set(tag),
+			db.Hashtag.UsageCount.Set(1),
+		).Update(
+			db.Hashtag.UsageCount.Increment(1),
+		).Exec(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, tag := range removed {
+		_, err := client.Hashtag.FindUnique(
+			db.Hashtag.Tag.Equals(tag),
+		).Update(
+			db.Hashtag.UsageCount.Decrement(1),
+		).Exec(ctx)
+		if err != nil && !errors.Is(err, db.ErrNotFound) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MergeHashtagSubjects adds the hashtags that are not already among the
+// subjects, compared without case.
+func MergeHashtagSubjects(subjects, hashtags []string) []string {
+	present := make(map[string]bool, len(subjects))
+	for _, subject := range subjects {
+		present[strings.ToLower(strings.TrimSpace(subject))] = true
+	}
+
+	merged := append([]string{}, subjects...)
+	for _, tag := range hashtags {
+		if !present[tag] {
+			present[tag] = true
+			merged = append(merged, tag)
+		}
+	}
+
+	return merged
+}
@@ -0,0 +1,92 @@
+package utils
+
+import (
+	"context"
+	"time"
+	"vilow-be/pkg/feed"
+	"vilow-be/prisma/db"
+)
+
+const (
+	// FeedCandidatePool caps how many recent media are scored per request.
+	FeedCandidatePool = 500
+	// FeedSeenWindow is how long an item stays out of the feed after it was shown.
+	FeedSeenWindow = 72 * time.Hour
+)
+
+func FeedCandidate(media *db.MediaModel) feed.Candidate {
+	return feed.Candidate{
+		ID:        media.ID,
+		OwnerID:   media.UserID,
+		Subjects:  media.Subjects,
+		Likes:     len(media.RelationsMedia.Likes),
+		Dislikes:  len(media.RelationsMedia.Dislikes),
+		Comments:  len(media.RelationsMedia.Comments),
+		CreatedAt: media.CreatedAt,
+	}
+}
+
+// LoadFeedViewer collects the follows and recent impressions the ranker
+// needs for userID.
+func LoadFeedViewer(ctx context.Context, client *db.PrismaClient, userID string, subjects []string, now time.Time) (feed.Viewer, error) {
+	viewer := feed.Viewer{
+		ID:        userID,
+		Subjects:  subjects,
+		Following: make(map[string]bool),
+		Seen:      make(map[string]time.Time),
+	}
+
+	follows, err := client.Follow.FindMany(
+		db.Follow.FollowerID.Equals(userID),
+	).Exec(ctx)
+	if err != nil {
+		return viewer, err
+	}
+
+	for _, follow := range follows {
+		viewer.Following[follow.FollowingID] = true
+	}
+
+	impressions, err := client.FeedImpression.FindMany(
+		db.FeedImpression.UserID.Equals(userID),
+		db.FeedImpression.SeenAt.Gte(now.Add(-FeedSeenWindow)),
+	).Exec(ctx)
+	if err != nil {
+		return viewer, err
+	}
+
+	for _, impression := range impressions {
+		viewer.Seen[impression.MediaID] = impression.SeenAt
+	}
+
+	return viewer, nil
+}
+
+// RecordFeedImpressions marks the served media as seen by userID.
+func RecordFeedImpressions(ctx context.Context, client *db.PrismaClient, userID string, mediaIDs []string) error {
+	now := time.Now()
+
+	for _, mediaID := range mediaIDs {
+		_, err := client.FeedImpression.UpsertOne(
+			db.FeedImpression.UserIDMediaID(
+				db.FeedImpression.UserID.Equals(userID),
+				db.FeedImpression.MediaID.Equals(mediaID),
+			),
+		).Create(
+			db.FeedImpression.User.Link(
+				db.User.ID.Equals(userID),
+			),
+			db.FeedImpression.Media.Link(
+				db.Media.ID.Equals(mediaID),
+			),
+			db.FeedImpression.SeenAt.Set(now),
+		).Update(
+			db.FeedImpression.SeenAt.Set(now),
+		).Exec(ctx)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,53 @@
+package utils
+
+import (
+	"context"
+	"time"
+	"vilow-be/pkg/dto"
+	"vilow-be/prisma/db"
+)
+
+// RecordWatchHistory stores where userID is in mediaID so playback can be
+// resumed. Starting a video again clears its completed flag.
+func RecordWatchHistory(ctx context.Context, client *db.PrismaClient, userID, mediaID string, position float64, completed bool) error {
+	now := time.Now()
+
+	_, err := client.WatchHistory.UpsertOne(
+		db.WatchHistory.UserIDMediaID(
+			db.WatchHistory.UserID.Equals(userID),
+			db.WatchHistory.MediaID.Equals(mediaID),
+		),
+	).Create(
+		db.WatchHistory.User.Link(
+			db.User.ID.Equals(userID),
+		),
+		db.WatchHistory.Media.Link(
+			db.Media.ID.Equals(mediaID),
+		),
+		db.WatchHistory.Position.Set(position),
+		db.WatchHistory.Completed.Set(completed),
+		db.WatchHistory.WatchedAt.Set(now),
+	).Update(
+		db.WatchHistory.Position.Set(position),
+		db.WatchHistory.Completed.Set(completed),
+		db.WatchHistory.WatchedAt.Set(now),
+	).Exec(ctx)
+
+	return err
+}
+
+func BuildHistoryEntry(entry *db.WatchHistoryModel) dto.HistoryEntry {
+	response := dto.HistoryEntry{
+		ID:        entry.ID,
+		Position:  entry.Position,
+		Completed: entry.Completed,
+		WatchedAt: entry.WatchedAt,
+		Media:     dto.Media{ID: entry.MediaID},
+	}
+
+	if entry.RelationsWatchHistory.Media != nil {
+		response.Media = BuildMediaResponse(entry.RelationsWatchHistory.Media)
+	}
+
+	return response
+}
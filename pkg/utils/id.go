@@ -0,0 +1,33 @@
+package utils
+
+import (
+	"crypto/rand"
+	"math/big"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewID returns an id in the shape of the cuid() ids Prisma generates: "c",
+// the time in milliseconds and random characters, all in base 36. It is for
+// records whose id has to be known before they are written, such as a
+// conversation created in the same transaction as its participants.
+func NewID() string {
+	millis := strconv.FormatInt(time.Now().UnixMilli(), 36)
+
+	var id strings.Builder
+	id.WriteString("c")
+	id.WriteString(strings.Repeat("0", max(0, 8-len(millis))))
+	id.WriteString(millis)
+
+	limit := big.NewInt(36)
+	for i := 0; i < 16; i++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			panic(err)
+		}
+		id.WriteString(strconv.FormatInt(n.Int64(), 36))
+	}
+
+	return id.String()
+}
@@ -2,7 +2,6 @@ package utils
 
 import (
 	"errors"
-	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt"
@@ -13,10 +12,10 @@ type Claims struct {
 	jwt.StandardClaims
 }
 
-func VerifyToken(tokenString string, userID string) (*jwt.Token, error) {
-	jwtKey := []byte(os.Getenv("JWT_SECRET_KEY"))
+func VerifyToken(tokenString string, userID string, secret string) (*jwt.Token, error) {
+	jwtKey := []byte(secret)
 	if len(jwtKey) == 0 {
-		return nil, errors.New("JWT_SECRET_KEY is not set")
+		return nil, errors.New("JWT secret is not set")
 	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
@@ -33,13 +32,13 @@ func VerifyToken(tokenString string, userID string) (*jwt.Token, error) {
 	return token, nil
 }
 
-func GenerateToken(userID string) (string, error) {
-	jwtKey := []byte(os.Getenv("JWT_SECRET_KEY"))
+func GenerateToken(userID string, secret string, ttl time.Duration) (string, error) {
+	jwtKey := []byte(secret)
 	if len(jwtKey) == 0 {
-		return "", errors.New("JWT_SECRET_KEY is not set")
+		return "", errors.New("JWT secret is not set")
 	}
 
-	expirationTime := time.Now().Add(5 * time.Hour)
+	expirationTime := time.Now().Add(ttl)
 	claims := &Claims{
 		UserID: userID,
 		StandardClaims: jwt.StandardClaims{
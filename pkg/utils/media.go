@@ -0,0 +1,125 @@
+package utils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
+	"vilow-be/prisma/db"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+// BuildMediaResponse maps a media record to its response shape. Relations are
+// only mapped when they were fetched alongside the media.
+func BuildMediaResponse(media *db.MediaModel) dto.Media {
+	response := dto.Media{
+		ID:          media.ID,
+		Name:        media.Name,
+		Path:        media.Path,
+		Description: media.Description,
+		Subjects:    media.Subjects,
+		Hashtags:    media.Hashtags,
+		UserID:      media.UserID,
+		ViewCount:   media.ViewCount,
+		WatchTime:   media.WatchTime,
+		Duration:    media.Duration,
+		Hidden:      media.Hidden,
+		CreatedAt:   media.CreatedAt,
+		Likes:       make([]dto.Like, len(media.RelationsMedia.Likes)),
+		Dislikes:    make([]dto.Dislike, len(media.RelationsMedia.Dislikes)),
+		Comments:    make([]dto.Comment, len(media.RelationsMedia.Comments)),
+	}
+
+	rawEntities, _ := media.Entities()
+	response.Entities = DecodeEntities(rawEntities)
+
+	rawChapters, _ := media.Chapters()
+	response.Chapters = DecodeChapters(rawChapters)
+
+	for i, like := range media.RelationsMedia.Likes {
+		response.Likes[i] = dto.Like{
+			ID:    like.ID,
+			User:  dto.User{ID: like.UserID},
+			Media: dto.Media{ID: like.MediaID},
+		}
+	}
+
+	for i, dislike := range media.RelationsMedia.Dislikes {
+		response.Dislikes[i] = dto.Dislike{
+			ID:    dislike.ID,
+			User:  dto.User{ID: dislike.UserID},
+			Media: dto.Media{ID: dislike.MediaID},
+		}
+	}
+
+	for i, comment := range media.RelationsMedia.Comments {
+		rawEntities, _ := comment.Entities()
+		response.Comments[i] = dto.Comment{
+			ID:        comment.ID,
+			User:      dto.User{ID: comment.UserID},
+			Media:     dto.Media{ID: comment.MediaID},
+			Content:   comment.Content,
+			Entities:  DecodeEntities(rawEntities),
+			CreatedAt: comment.CreatedAt,
+		}
+	}
+
+	return response
+}
+
+// DeleteMedia removes the media record with everything that references it,
+// since the database does not cascade deletes, and then its files from
+// storage. ops run in the same transaction as the records, for callers
+// whose own changes must not outlive a failed delete.
+func DeleteMedia(ctx context.Context, client *db.PrismaClient, minioClient *minio.Client, bucketName string, media *db.MediaModel, ops ...transaction.Param) error {
+	captions, err := client.Caption.FindMany(db.Caption.MediaID.Equals(media.ID)).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching captions associated with media: %w", err)
+	}
+
+	ops = append(ops,
+		client.Like.FindMany(db.Like.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.Dislike.FindMany(db.Dislike.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.Comment.FindMany(db.Comment.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.PlaylistItem.FindMany(db.PlaylistItem.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.TimelineEntry.FindMany(db.TimelineEntry.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.FeedImpression.FindMany(db.FeedImpression.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.WatchHistory.FindMany(db.WatchHistory.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.MediaDailyStat.FindMany(db.MediaDailyStat.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.MediaDailyViewer.FindMany(db.MediaDailyViewer.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.Bookmark.FindMany(db.Bookmark.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.Caption.FindMany(db.Caption.MediaID.Equals(media.ID)).Delete().Tx(),
+		client.Media.FindUnique(db.Media.ID.Equals(media.ID)).Delete().Tx(),
+	)
+
+	if err := client.Prisma.Transaction(ops...).Exec(ctx); err != nil {
+		return fmt.Errorf("deleting media from database: %w", err)
+	}
+
+	logger := logging.FromContext(ctx)
+
+	// Files left behind only take up space, so failing to remove them does
+	// not fail the delete. The path is the object URL MinIO returned on
+	// upload, whatever the endpoint was at the time.
+	objectName := media.Path
+	if _, name, found := strings.Cut(media.Path, "/"+bucketName+"/"); found {
+		objectName = name
+	}
+	if err := minioClient.RemoveObject(ctx, bucketName, objectName, minio.RemoveObjectOptions{}); err != nil {
+		logger.Error("Error deleting media file from MinIO", "media_id", media.ID, "error", err)
+	}
+	for _, caption := range captions {
+		if err := minioClient.RemoveObject(ctx, bucketName, caption.ObjectName, minio.RemoveObjectOptions{}); err != nil {
+			logger.Error("Error deleting caption from MinIO", "object", caption.ObjectName, "error", err)
+		}
+	}
+
+	if err := UpdateHashtagCounts(ctx, client, nil, media.Hashtags); err != nil {
+		logger.Error("Error updating hashtag counts", "media_id", media.ID, "error", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"vilow-be/pkg/dto"
+	"vilow-be/prisma/db"
+)
+
+// BuildMessageResponse maps a message to its response shape. The sender
+// and the shared media are only filled in when they were fetched.
+func BuildMessageResponse(message *db.MessageModel) dto.Message {
+	response := dto.Message{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		Sender:         dto.User{ID: message.SenderID},
+		Content:        message.Content,
+		CreatedAt:      message.CreatedAt,
+	}
+
+	if sender := message.RelationsMessage.Sender; sender != nil {
+		response.Sender = dto.User{ID: sender.ID, Name: sender.Name, StrID: sender.StrID}
+	}
+
+	if media, ok := message.Media(); ok {
+		mediaResponse := BuildMediaResponse(media)
+		response.Media = &mediaResponse
+	} else if mediaID, ok := message.MediaID(); ok {
+		response.Media = &dto.Media{ID: mediaID}
+	}
+
+	return response
+}
+
+// BuildConversationResponse maps a conversation fetched with its
+// participants and latest message to what viewerID sees of it.
+func BuildConversationResponse(conversation *db.ConversationModel, viewerID string) dto.Conversation {
+	response := dto.Conversation{
+		ID:            conversation.ID,
+		Title:         conversation.Title,
+		IsGroup:       conversation.IsGroup,
+		Participants:  []dto.Participant{},
+		LastMessageAt: conversation.LastMessageAt,
+		CreatedAt:     conversation.CreatedAt,
+	}
+
+	var viewer *db.ParticipantModel
+	for i, participant := range conversation.RelationsConversation.Participants {
+		if participant.UserID == viewerID {
+			viewer = &conversation.RelationsConversation.Participants[i]
+		}
+
+		entry := dto.Participant{
+			User:     dto.User{ID: participant.UserID},
+			JoinedAt: participant.JoinedAt,
+		}
+		if user := participant.RelationsParticipant.User; user != nil {
+			entry.User = dto.User{ID: user.ID, Name: user.Name, StrID: user.StrID}
+		}
+		if lastReadAt, ok := participant.LastReadAt(); ok {
+			entry.LastReadAt = &lastReadAt
+		}
+		if lastReadMessageID, ok := participant.LastReadMessageID(); ok {
+			entry.LastReadMessageID = lastReadMessageID
+		}
+
+		response.Participants = append(response.Participants, entry)
+	}
+
+	if messages := conversation.RelationsConversation.Messages; len(messages) > 0 {
+		lastMessage := BuildMessageResponse(&messages[0])
+		response.LastMessage = &lastMessage
+
+		if viewer != nil && messages[0].SenderID != viewerID {
+			lastReadAt, ok := viewer.LastReadAt()
+			response.Unread = !ok || lastReadAt.Before(messages[0].CreatedAt)
+		}
+	}
+
+	return response
+}
+
+// ConversationParticipantIDs returns the users taking part in a
+// conversation fetched with its participants.
+func ConversationParticipantIDs(conversation *db.ConversationModel) []string {
+	userIDs := make([]string, len(conversation.RelationsConversation.Participants))
+	for i, participant := range conversation.RelationsConversation.Participants {
+		userIDs[i] = participant.UserID
+	}
+
+	return userIDs
+}
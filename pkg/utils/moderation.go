@@ -0,0 +1,118 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/models"
+	"vilow-be/prisma/db"
+
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
+)
+
+// IsModerator reports whether role may review reports and see hidden content.
+func IsModerator(role string) bool {
+	return role == models.RoleModerator || role == models.RoleAdmin
+}
+
+// ValidateReport checks the target type and reason of a new report.
+func ValidateReport(report *models.Report) error {
+	switch report.TargetType {
+	case models.ReportTargetMedia, models.ReportTargetComment, models.ReportTargetUser:
+	default:
+		return errors.New("targetType must be media, comment or user")
+	}
+
+	if report.TargetID == "" {
+		return errors.New("targetId is required")
+	}
+
+	for _, reason := range models.ReportReasons {
+		if report.Reason == reason {
+			return nil
+		}
+	}
+	return errors.New("reason is not supported")
+}
+
+// ReportTargetOwner returns the ID of the user responsible for the reported
+// content, or db.ErrNotFound when it no longer exists.
+func ReportTargetOwner(ctx context.Context, client *db.PrismaClient, targetType, targetID string) (string, error) {
+	switch targetType {
+	case models.ReportTargetMedia:
+		media, err := client.Media.FindUnique(db.Media.ID.Equals(targetID)).Exec(ctx)
+		if err != nil {
+			return "", err
+		}
+		return media.UserID, nil
+	case models.ReportTargetComment:
+		comment, err := client.Comment.FindUnique(db.Comment.ID.Equals(targetID)).Exec(ctx)
+		if err != nil {
+			return "", err
+		}
+		return comment.UserID, nil
+	default:
+		user, err := client.User.FindUnique(db.User.ID.Equals(targetID)).Exec(ctx)
+		if err != nil {
+			return "", err
+		}
+		return user.ID, nil
+	}
+}
+
+// HideReportTarget hides the reported content from everyone but its owner
+// and moderators, as an operation of the transaction resolving the report.
+func HideReportTarget(client *db.PrismaClient, targetType, targetID string) transaction.Param {
+	switch targetType {
+	case models.ReportTargetMedia:
+		return client.Media.FindUnique(
+			db.Media.ID.Equals(targetID),
+		).Update(
+			db.Media.Hidden.Set(true),
+		).Tx()
+	case models.ReportTargetComment:
+		return client.Comment.FindUnique(
+			db.Comment.ID.Equals(targetID),
+		).Update(
+			db.Comment.Hidden.Set(true),
+		).Tx()
+	default:
+		return client.User.FindUnique(
+			db.User.ID.Equals(targetID),
+		).Update(
+			db.User.Hidden.Set(true),
+		).Tx()
+	}
+}
+
+func BuildReportResponse(report *db.ReportModel) dto.Report {
+	response := dto.Report{
+		ID:         report.ID,
+		ReporterID: report.ReporterID,
+		TargetType: report.TargetType,
+		TargetID:   report.TargetID,
+		Reason:     report.Reason,
+		Notes:      report.Notes,
+		Status:     report.Status,
+		Actions:    make([]dto.ModerationAction, len(report.RelationsReport.Actions)),
+		CreatedAt:  report.CreatedAt,
+	}
+
+	if resolvedAt, ok := report.ResolvedAt(); ok {
+		response.ResolvedAt = &resolvedAt
+	}
+
+	for i, action := range report.RelationsReport.Actions {
+		response.Actions[i] = dto.ModerationAction{
+			ID:        action.ID,
+			Action:    action.Action,
+			Notes:     action.Notes,
+			CreatedAt: action.CreatedAt,
+		}
+		if moderatorID, ok := action.ModeratorID(); ok {
+			response.Actions[i].ModeratorID = &moderatorID
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"context"
+	"vilow-be/prisma/db"
+)
+
+// Notify stores a notification for userID.
+func Notify(ctx context.Context, client *db.PrismaClient, userID string, content string) error {
+	_, err := client.Notification.CreateOne(
+		db.Notification.User.Link(
+			db.User.ID.Equals(userID),
+		),
+		db.Notification.Content.Set(content),
+	).Exec(ctx)
+
+	return err
+}
+
+// NotifyFrom stores a notification caused by actorID. Nothing is stored when
+// either of them blocked the other.
+func NotifyFrom(ctx context.Context, client *db.PrismaClient, actorID, userID string, content string) error {
+	blocked, err := IsBlocked(ctx, client, actorID, userID)
+	if err != nil || blocked {
+		return err
+	}
+
+	return Notify(ctx, client, userID, content)
+}
@@ -0,0 +1,170 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"vilow-be/pkg/dto"
+	"vilow-be/pkg/models"
+	"vilow-be/prisma/db"
+)
+
+const watchLaterTitle = "Watch later"
+
+// ValidatePlaylist checks the playlist fields that were given. An empty
+// visibility is accepted and left to the caller's default.
+func ValidatePlaylist(playlist *models.PlaylistInput) bool {
+	switch playlist.Visibility {
+	case "", models.PlaylistPublic, models.PlaylistUnlisted, models.PlaylistPrivate:
+		return true
+	default:
+		return false
+	}
+}
+
+// CanViewPlaylist reports whether userID may read the playlist. Unlisted
+// playlists are readable by anyone holding their ID, private ones only by
+// their owner.
+func CanViewPlaylist(playlist *db.PlaylistModel, userID string) bool {
+	return playlist.UserID == userID || playlist.Visibility != models.PlaylistPrivate
+}
+
+// PlaylistItemsFetch fetches playlist items in position order together with
+// their media.
+func PlaylistItemsFetch() db.PlaylistRelationWith {
+	return db.Playlist.Items.Fetch().OrderBy(
+		db.PlaylistItem.Position.Order(db.ASC),
+	).With(
+		db.PlaylistItem.Media.Fetch(),
+	)
+}
+
+// GetOrCreateWatchLater returns the user's "Watch later" system playlist,
+// creating it on first use. It is upserted on its system key, so concurrent
+// first requests cannot create two of them.
+func GetOrCreateWatchLater(ctx context.Context, client *db.PrismaClient, userID string) (*db.PlaylistModel, error) {
+	key := db.Playlist.UserIDSystemKey(
+		db.Playlist.UserID.Equals(userID),
+		db.Playlist.SystemKey.Equals(models.PlaylistKindWatchLater),
+	)
+
+	playlist, err := client.Playlist.FindUnique(key).With(
+		PlaylistItemsFetch(),
+	).Exec(ctx)
+	if err == nil {
+		return playlist, nil
+	}
+	if !errors.Is(err, db.ErrNotFound) {
+		return nil, err
+	}
+
+	_, upsertErr := client.Playlist.UpsertOne(key).Create(
+		db.Playlist.Title.Set(watchLaterTitle),
+		db.Playlist.Description.Set(""),
+		db.Playlist.User.Link(
+			db.User.ID.Equals(userID),
+		),
+		db.Playlist.Visibility.Set(models.PlaylistPrivate),
+		db.Playlist.Kind.Set(models.PlaylistKindWatchLater),
+		db.Playlist.SystemKey.Set(models.PlaylistKindWatchLater),
+	).Update().Exec(ctx)
+
+	// A concurrent request may win the insert, the unique key then fails
+	// this one and the playlist it created is read back below.
+	playlist, err = client.Playlist.FindUnique(key).With(
+		PlaylistItemsFetch(),
+	).Exec(ctx)
+	if err != nil && upsertErr != nil {
+		return nil, upsertErr
+	}
+
+	return playlist, err
+}
+
+// FilterPlaylistItems drops the items of playlists whose media viewerID may
+// not see: hidden media of others, media of users blocked either way and
+// media of private accounts viewerID does not follow. A playlist must not
+// become a way around those rules.
+func FilterPlaylistItems(ctx context.Context, client *db.PrismaClient, viewerID string, playlists ...*db.PlaylistModel) error {
+	mediaIDs := []string{}
+	for _, playlist := range playlists {
+		for _, item := range playlist.RelationsPlaylist.Items {
+			mediaIDs = append(mediaIDs, item.MediaID)
+		}
+	}
+	if len(mediaIDs) == 0 {
+		return nil
+	}
+
+	blockers, err := BlockerIDs(ctx, client, viewerID)
+	if err != nil {
+		return err
+	}
+	blocked, err := BlockedIDs(ctx, client, viewerID)
+	if err != nil {
+		return err
+	}
+
+	visible, err := VisibleMediaFilter(ctx, client, viewerID)
+	if err != nil {
+		return err
+	}
+
+	medias, err := client.Media.FindMany(
+		db.Media.ID.In(mediaIDs),
+		db.Media.UserID.NotIn(append(blockers, blocked...)),
+		db.Media.Or(
+			db.Media.Hidden.Equals(false),
+			db.Media.UserID.Equals(viewerID),
+		),
+		visible,
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	allowed := make(map[string]bool, len(medias))
+	for _, media := range medias {
+		allowed[media.ID] = true
+	}
+
+	for _, playlist := range playlists {
+		items := make([]db.PlaylistItemModel, 0, len(playlist.RelationsPlaylist.Items))
+		for _, item := range playlist.RelationsPlaylist.Items {
+			if allowed[item.MediaID] {
+				items = append(items, item)
+			}
+		}
+		playlist.RelationsPlaylist.Items = items
+	}
+
+	return nil
+}
+
+func BuildPlaylistResponse(playlist *db.PlaylistModel) dto.Playlist {
+	response := dto.Playlist{
+		ID:          playlist.ID,
+		Title:       playlist.Title,
+		Description: playlist.Description,
+		Visibility:  playlist.Visibility,
+		Kind:        playlist.Kind,
+		UserID:      playlist.UserID,
+		Items:       make([]dto.PlaylistItem, len(playlist.RelationsPlaylist.Items)),
+		CreatedAt:   playlist.CreatedAt,
+		UpdatedAt:   playlist.UpdatedAt,
+	}
+
+	for i, item := range playlist.RelationsPlaylist.Items {
+		response.Items[i] = dto.PlaylistItem{
+			ID:       item.ID,
+			Position: item.Position,
+			AddedAt:  item.AddedAt,
+			Media:    dto.Media{ID: item.MediaID},
+		}
+
+		if item.RelationsPlaylistItem.Media != nil {
+			response.Items[i].Media = BuildMediaResponse(item.RelationsPlaylistItem.Media)
+		}
+	}
+
+	return response
+}
@@ -0,0 +1,75 @@
+package utils
+
+import (
+	"context"
+	"errors"
+	"vilow-be/prisma/db"
+)
+
+// IsFollowing reports whether followerID follows followingID.
+func IsFollowing(ctx context.Context, client *db.PrismaClient, followerID, followingID string) (bool, error) {
+	_, err := client.Follow.FindUnique(
+		db.Follow.FollowerIDFollowingID(
+			db.Follow.FollowerID.Equals(followerID),
+			db.Follow.FollowingID.Equals(followingID),
+		),
+	).Exec(ctx)
+
+	if errors.Is(err, db.ErrNotFound) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// CanViewContent reports whether viewerID may see the media and follower
+// lists of owner. Private accounts only share them with approved followers.
+func CanViewContent(ctx context.Context, client *db.PrismaClient, viewerID string, owner *db.UserModel) (bool, error) {
+	if !owner.IsPrivate || owner.ID == viewerID {
+		return true, nil
+	}
+
+	return IsFollowing(ctx, client, viewerID, owner.ID)
+}
+
+// CanViewMediaOf is CanViewContent for callers that only know the owner ID.
+func CanViewMediaOf(ctx context.Context, client *db.PrismaClient, viewerID, ownerID string) (bool, error) {
+	if ownerID == viewerID {
+		return true, nil
+	}
+
+	owner, err := client.User.FindUnique(
+		db.User.ID.Equals(ownerID),
+	).Exec(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	return CanViewContent(ctx, client, viewerID, owner)
+}
+
+// VisibleMediaFilter matches the media viewerID may see in lists: media of
+// public accounts plus media of the accounts viewerID follows or owns.
+func VisibleMediaFilter(ctx context.Context, client *db.PrismaClient, viewerID string) (db.MediaWhereParam, error) {
+	follows, err := client.Follow.FindMany(
+		db.Follow.FollowerID.Equals(viewerID),
+	).Exec(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	ownerIDs := make([]string, 0, len(follows)+1)
+	ownerIDs = append(ownerIDs, viewerID)
+	for _, follow := range follows {
+		ownerIDs = append(ownerIDs, follow.FollowingID)
+	}
+
+	return db.Media.Or(
+		db.Media.User.Where(
+			db.User.IsPrivate.Equals(false),
+		),
+		db.Media.UserID.In(ownerIDs),
+	), nil
+}
@@ -0,0 +1,85 @@
+package utils
+
+import (
+	"context"
+	"strings"
+	"vilow-be/pkg/search"
+	"vilow-be/prisma/db"
+)
+
+func MediaDocument(media *db.MediaModel) search.Document {
+	return search.Document{
+		ID:   media.ID,
+		Kind: search.KindMedia,
+		Fields: map[string]string{
+			"name":        media.Name,
+			"description": media.Description,
+			"subjects":    strings.Join(media.Subjects, " "),
+		},
+		Subjects:  media.Subjects,
+		OwnerID:   media.UserID,
+		CreatedAt: media.CreatedAt,
+	}
+}
+
+func UserDocument(user *db.UserModel) search.Document {
+	return search.Document{
+		ID:   user.ID,
+		Kind: search.KindUser,
+		Fields: map[string]string{
+			"name":        user.Name,
+			"strId":       user.StrID,
+			"description": user.Description,
+			"subjects":    strings.Join(user.Subjects, " "),
+		},
+		Subjects: user.Subjects,
+	}
+}
+
+// IndexMedia adds media to the index, or drops it while it is hidden.
+func IndexMedia(index search.Index, media *db.MediaModel) error {
+	if media.Hidden {
+		return index.Delete(search.KindMedia, media.ID)
+	}
+	return index.Upsert(MediaDocument(media))
+}
+
+// IndexUser adds user to the index, or drops them while they are hidden.
+func IndexUser(index search.Index, user *db.UserModel) error {
+	if user.Hidden {
+		return index.Delete(search.KindUser, user.ID)
+	}
+	return index.Upsert(UserDocument(user))
+}
+
+// BuildSearchIndex loads every media and user into the index. It is meant to
+// run once at startup, handlers keep the index current afterwards.
+func BuildSearchIndex(ctx context.Context, client *db.PrismaClient, index search.Index) error {
+	medias, err := client.Media.FindMany(
+		db.Media.Hidden.Equals(false),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range medias {
+		if err := index.Upsert(MediaDocument(&medias[i])); err != nil {
+			return err
+		}
+	}
+
+	users, err := client.User.FindMany(
+		db.User.Hidden.Equals(false),
+	).Exec(ctx)
+	if err != nil {
+		return err
+	}
+
+	for i := range users {
+		if err := index.Upsert(UserDocument(&users[i])); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,30 +1,23 @@
 package utils
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
-	"log"
 	"net/http"
-	"net/mail"
+	"vilow-be/pkg/apierror"
 	"vilow-be/pkg/dto"
+	"vilow-be/pkg/logging"
 	"vilow-be/pkg/models"
+	"vilow-be/pkg/search"
 	"vilow-be/prisma/db"
 
+	"github.com/minio/minio-go/v7"
+	"github.com/steebchen/prisma-client-go/runtime/transaction"
 	"golang.org/x/crypto/bcrypt"
 )
 
-func ValidateUser(user *models.User) bool {
-	if user.Email != "" {
-		_, err := mail.ParseAddress(user.Email)
-		if err != nil {
-			log.Printf("Error parsing email address: %v\n", err)
-			return false
-		}
-	}
-	return true
-}
-
-func BuildUpdateData(user *models.User) (updateData []db.UserSetParam, err error) {
+func BuildUpdateData(user *models.UserUpdate) (updateData []db.UserSetParam, err error) {
 	if user.Name != "" {
 		updateData = append(updateData, db.User.Name.Set(user.Name))
 	}
@@ -37,8 +30,7 @@ func BuildUpdateData(user *models.User) (updateData []db.UserSetParam, err error
 		var hashedPassword []byte
 		hashedPassword, err = bcrypt.GenerateFromPassword([]byte(user.Password), bcrypt.DefaultCost)
 		if err != nil {
-			log.Printf("Error generating password hash: %v\n", err)
-			return nil, err
+			return nil, fmt.Errorf("hashing password: %w", err)
 		}
 
 		updateData = append(updateData, db.User.Password.Set(string(hashedPassword)))
@@ -52,6 +44,10 @@ func BuildUpdateData(user *models.User) (updateData []db.UserSetParam, err error
 		updateData = append(updateData, db.User.Description.Set(user.Description))
 	}
 
+	if user.IsPrivate != nil {
+		updateData = append(updateData, db.User.IsPrivate.Set(*user.IsPrivate))
+	}
+
 	return updateData, nil
 }
 
@@ -62,46 +58,28 @@ func BuildResponse(existingUser *db.UserModel) (*dto.User, error) {
 		Email:       existingUser.Email,
 		StrID:       existingUser.StrID,
 		Description: existingUser.Description,
-		Medias:      make([]dto.Media, len(existingUser.Medias())),
-	}
-
-	for i, media := range existingUser.Medias() {
-		response.Medias[i] = dto.Media{
-			ID:          media.ID,
-			Name:        media.Name,
-			Path:        media.Path,
-			Description: media.Description,
-			UserID:      media.UserID,
-			Likes:       make([]dto.Like, len(media.Likes())),
-			Comments:    make([]dto.Comment, len(media.Comments())),
-		}
+		Subjects:    existingUser.Subjects,
+		IsPrivate:   existingUser.IsPrivate,
+		Medias:      make([]dto.Media, len(existingUser.RelationsUser.Medias)),
+		Playlists:   make([]dto.Playlist, len(existingUser.RelationsUser.Playlists)),
+	}
 
-		for j, like := range media.Likes() {
-			response.Medias[i].Likes[j] = dto.Like{
-				ID:    like.ID,
-				User:  dto.User{ID: like.User().ID},
-				Media: dto.Media{ID: like.Media().ID},
-			}
-		}
+	for i := range existingUser.RelationsUser.Medias {
+		response.Medias[i] = BuildMediaResponse(&existingUser.RelationsUser.Medias[i])
+	}
 
-		for k, comment := range media.Comments() {
-			response.Medias[i].Comments[k] = dto.Comment{
-				ID:      comment.ID,
-				User:    dto.User{ID: comment.User().ID},
-				Media:   dto.Media{ID: comment.Media().ID},
-				Content: comment.Content,
-			}
-		}
+	for i := range existingUser.RelationsUser.Playlists {
+		response.Playlists[i] = BuildPlaylistResponse(&existingUser.RelationsUser.Playlists[i])
 	}
 
 	return response, nil
 }
 
-func SendResponse(w http.ResponseWriter, response *dto.User) {
+func SendResponse(w http.ResponseWriter, r *http.Request, response *dto.User) {
 	jsonData, err := json.Marshal(response)
 	if err != nil {
-		http.Error(w, fmt.Sprintf("Error converting to JSON: %v", err), http.StatusInternalServerError)
-		log.Printf("Error converting to JSON: %v\n", err)
+		logging.FromContext(r.Context()).Error("Error converting user to JSON", "error", err)
+		apierror.Error(w, r, "Error converting user to JSON", http.StatusInternalServerError)
 		return
 	}
 
@@ -109,3 +87,81 @@ func SendResponse(w http.ResponseWriter, response *dto.User) {
 
 	fmt.Fprintf(w, "%s", jsonString)
 }
+
+// DeleteUser removes a user with everything that references them, since the
+// database does not cascade deletes. Their media go first, one by one like
+// DeleteMedia does, then the rest in a single transaction. Moderation
+// actions the user took as a moderator stay as the record of the decision,
+// without a moderator.
+func DeleteUser(ctx context.Context, client *db.PrismaClient, minioClient *minio.Client, bucketName string, index search.Index, user *db.UserModel) error {
+	medias, err := client.Media.FindMany(db.Media.UserID.Equals(user.ID)).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching media of user: %w", err)
+	}
+	for i := range medias {
+		if err := DeleteMedia(ctx, client, minioClient, bucketName, &medias[i]); err != nil {
+			return err
+		}
+		if err := index.Delete(search.KindMedia, medias[i].ID); err != nil {
+			logging.FromContext(ctx).Error("Error removing media from the search index", "media_id", medias[i].ID, "error", err)
+		}
+	}
+
+	following, err := client.Follow.FindMany(db.Follow.FollowerID.Equals(user.ID)).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching follows of user: %w", err)
+	}
+	followingIDs := make([]string, len(following))
+	for i, follow := range following {
+		followingIDs[i] = follow.FollowingID
+	}
+
+	reports, err := client.Report.FindMany(db.Report.ReporterID.Equals(user.ID)).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching reports of user: %w", err)
+	}
+	reportIDs := make([]string, len(reports))
+	for i, report := range reports {
+		reportIDs[i] = report.ID
+	}
+
+	playlists, err := client.Playlist.FindMany(db.Playlist.UserID.Equals(user.ID)).Exec(ctx)
+	if err != nil {
+		return fmt.Errorf("fetching playlists of user: %w", err)
+	}
+	playlistIDs := make([]string, len(playlists))
+	for i, playlist := range playlists {
+		playlistIDs[i] = playlist.ID
+	}
+
+	ops := []transaction.Param{
+		client.User.FindMany(db.User.ID.In(followingIDs)).Update(db.User.FollowerCount.Decrement(1)).Tx(),
+		client.Follow.FindMany(db.Follow.Or(db.Follow.FollowerID.Equals(user.ID), db.Follow.FollowingID.Equals(user.ID))).Delete().Tx(),
+		client.FollowRequest.FindMany(db.FollowRequest.Or(db.FollowRequest.RequesterID.Equals(user.ID), db.FollowRequest.TargetID.Equals(user.ID))).Delete().Tx(),
+		client.Block.FindMany(db.Block.Or(db.Block.BlockerID.Equals(user.ID), db.Block.BlockedID.Equals(user.ID))).Delete().Tx(),
+		client.Mute.FindMany(db.Mute.Or(db.Mute.MuterID.Equals(user.ID), db.Mute.MutedID.Equals(user.ID))).Delete().Tx(),
+		client.Notification.FindMany(db.Notification.UserID.Equals(user.ID)).Delete().Tx(),
+		client.Like.FindMany(db.Like.UserID.Equals(user.ID)).Delete().Tx(),
+		client.Dislike.FindMany(db.Dislike.UserID.Equals(user.ID)).Delete().Tx(),
+		client.Comment.FindMany(db.Comment.UserID.Equals(user.ID)).Delete().Tx(),
+		client.PlaylistItem.FindMany(db.PlaylistItem.PlaylistID.In(playlistIDs)).Delete().Tx(),
+		client.Playlist.FindMany(db.Playlist.UserID.Equals(user.ID)).Delete().Tx(),
+		client.FeedImpression.FindMany(db.FeedImpression.UserID.Equals(user.ID)).Delete().Tx(),
+		client.TimelineEntry.FindMany(db.TimelineEntry.UserID.Equals(user.ID)).Delete().Tx(),
+		client.WatchHistory.FindMany(db.WatchHistory.UserID.Equals(user.ID)).Delete().Tx(),
+		client.MediaDailyViewer.FindMany(db.MediaDailyViewer.UserID.Equals(user.ID)).Delete().Tx(),
+		client.Bookmark.FindMany(db.Bookmark.UserID.Equals(user.ID)).Delete().Tx(),
+		client.ModerationAction.FindMany(db.ModerationAction.ReportID.In(reportIDs)).Delete().Tx(),
+		client.Report.FindMany(db.Report.ReporterID.Equals(user.ID)).Delete().Tx(),
+		client.Message.FindMany(db.Message.SenderID.Equals(user.ID)).Delete().Tx(),
+		client.Participant.FindMany(db.Participant.UserID.Equals(user.ID)).Delete().Tx(),
+		client.ModerationAction.FindMany(db.ModerationAction.ModeratorID.Equals(user.ID)).Update(db.ModerationAction.ModeratorID.SetOptional(nil)).Tx(),
+		client.User.FindUnique(db.User.ID.Equals(user.ID)).Delete().Tx(),
+	}
+
+	if err := client.Prisma.Transaction(ops...).Exec(ctx); err != nil {
+		return fmt.Errorf("deleting user from database: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/models"
+	"vilow-be/pkg/validation"
+)
+
+// ValidateNewUser checks a sign-up, where every field but the description
+// is required.
+func ValidateNewUser(user *models.Signup) *apierror.APIError {
+	v := validation.New()
+	v.Required("name", user.Name, validation.MaxLength(models.MaxUserNameLength))
+	v.Required("email", user.Email, validation.Email)
+	v.Required("password", user.Password, validation.Password)
+	v.Required("strId", user.StrID, validation.StrID)
+	v.Optional("description", user.Description, validation.MaxLength(models.MaxUserDescriptionLength))
+
+	return v.Err()
+}
+
+// ValidateUserUpdate checks a profile update, where empty fields are left
+// unchanged.
+func ValidateUserUpdate(user *models.UserUpdate) *apierror.APIError {
+	v := validation.New()
+	v.Optional("name", user.Name, validation.MaxLength(models.MaxUserNameLength))
+	v.Optional("email", user.Email, validation.Email)
+	v.Optional("password", user.Password, validation.Password)
+	v.Optional("strId", user.StrID, validation.StrID)
+	v.Optional("description", user.Description, validation.MaxLength(models.MaxUserDescriptionLength))
+
+	return v.Err()
+}
+
+// ValidateLogin only checks that credentials were given. Password strength
+// is not enforced here, accounts older than the rules must still log in.
+func ValidateLogin(user *models.Credentials) *apierror.APIError {
+	v := validation.New()
+	v.Required("email", user.Email)
+	v.Required("password", user.Password)
+
+	return v.Err()
+}
+
+// ValidateMedia checks the form fields of a media upload or update. Updates
+// replace every field, so the name is required on both.
+func ValidateMedia(name, description string, subjects []string) *apierror.APIError {
+	v := validation.New()
+	v.Required("name", name, validation.MaxLength(models.MaxMediaNameLength))
+	v.Optional("description", description, validation.MaxLength(models.MaxMediaDescriptionLength))
+	v.List("subjects", subjects, models.MaxMediaSubjects, validation.MaxLength(models.MaxSubjectLength))
+
+	return v.Err()
+}
+
+// ValidateReaction checks the type of a reaction.
+func ValidateReaction(reaction *models.Reaction) *apierror.APIError {
+	v := validation.New()
+	v.Required("type", reaction.Type, validation.OneOf(models.ReactionLike, models.ReactionDislike))
+
+	return v.Err()
+}
@@ -0,0 +1,172 @@
+package validation
+
+import (
+	"fmt"
+	"net/mail"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+	"vilow-be/pkg/apierror"
+	"vilow-be/pkg/entities"
+)
+
+// A strId is what mentions point at, so it is the whole of a mention handle.
+var strIDPattern = regexp.MustCompile(`^(?:` + entities.HandlePattern + `)$`)
+
+// Rule checks one value and returns the message shown to the client when the
+// value is invalid, or "" when it is fine.
+type Rule func(value string) string
+
+// Validator collects the field errors of a request. Each field reports its
+// first failing rule only.
+type Validator struct {
+	fields []apierror.FieldError
+}
+
+func New() *Validator {
+	return &Validator{}
+}
+
+// Required checks a field that must be present and not blank.
+func (v *Validator) Required(field, value string, rules ...Rule) {
+	if strings.TrimSpace(value) == "" {
+		v.Add(field, "is required")
+		return
+	}
+
+	v.check(field, value, rules)
+}
+
+// Optional checks a field only when it was given, empty meaning unset.
+func (v *Validator) Optional(field, value string, rules ...Rule) {
+	if value == "" {
+		return
+	}
+
+	v.check(field, value, rules)
+}
+
+// List checks a repeated field: at most max values, each passing rules.
+// Invalid values are reported as field[i].
+func (v *Validator) List(field string, values []string, max int, rules ...Rule) {
+	if len(values) > max {
+		v.Add(field, fmt.Sprintf("must have at most %d values", max))
+		return
+	}
+
+	for i, value := range values {
+		name := fmt.Sprintf("%s[%d]", field, i)
+		if strings.TrimSpace(value) == "" {
+			v.Add(name, "must not be blank")
+			continue
+		}
+		v.check(name, value, rules)
+	}
+}
+
+// Add reports field as invalid, for checks that do not fit a Rule.
+func (v *Validator) Add(field, message string) {
+	v.fields = append(v.fields, apierror.FieldError{Field: field, Message: message})
+}
+
+// Err returns the validation error listing every invalid field, or nil when
+// the request is valid.
+func (v *Validator) Err() *apierror.APIError {
+	if len(v.fields) == 0 {
+		return nil
+	}
+
+	return apierror.Validation(v.fields...)
+}
+
+func (v *Validator) check(field, value string, rules []Rule) {
+	for _, rule := range rules {
+		if message := rule(value); message != "" {
+			v.Add(field, message)
+			return
+		}
+	}
+}
+
+// Length limits a value to between min and max characters.
+func Length(min, max int) Rule {
+	return func(value string) string {
+		length := utf8.RuneCountInString(value)
+		if length < min || length > max {
+			return fmt.Sprintf("must be between %d and %d characters", min, max)
+		}
+		return ""
+	}
+}
+
+// MaxLength limits a value to max characters.
+func MaxLength(max int) Rule {
+	return func(value string) string {
+		if utf8.RuneCountInString(value) > max {
+			return fmt.Sprintf("must be at most %d characters", max)
+		}
+		return ""
+	}
+}
+
+// Pattern requires a value to match pattern, message describing the format.
+func Pattern(pattern *regexp.Regexp, message string) Rule {
+	return func(value string) string {
+		if !pattern.MatchString(value) {
+			return message
+		}
+		return ""
+	}
+}
+
+// OneOf requires a value to be one of values.
+func OneOf(values ...string) Rule {
+	return func(value string) string {
+		for _, allowed := range values {
+			if value == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(values, ", ")
+	}
+}
+
+// Email requires a bare e-mail address, without a display name.
+func Email(value string) string {
+	address, err := mail.ParseAddress(value)
+	if err != nil || address.Address != value {
+		return "must be a valid e-mail address"
+	}
+	return ""
+}
+
+// StrID requires a handle of 3 to 30 characters that mentions can point at.
+func StrID(value string) string {
+	if message := Length(3, 30)(value); message != "" {
+		return message
+	}
+	if !strIDPattern.MatchString(value) {
+		return "may only contain letters, digits, underscores, dots and dashes, and must start and end with a letter, digit or underscore"
+	}
+	return ""
+}
+
+// Password requires at least 8 characters with a letter and a digit. bcrypt
+// ignores everything past 72 bytes, so longer passwords are refused rather
+// than silently truncated.
+func Password(value string) string {
+	if utf8.RuneCountInString(value) < 8 {
+		return "must be at least 8 characters"
+	}
+	if len(value) > 72 {
+		return "must be at most 72 bytes"
+	}
+
+	hasLetter := strings.IndexFunc(value, unicode.IsLetter) >= 0
+	hasDigit := strings.IndexFunc(value, unicode.IsDigit) >= 0
+	if !hasLetter || !hasDigit {
+		return "must contain at least one letter and one digit"
+	}
+	return ""
+}
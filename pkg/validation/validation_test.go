@@ -0,0 +1,141 @@
+package validation
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"vilow-be/pkg/apierror"
+)
+
+func TestRules(t *testing.T) {
+	tests := []struct {
+		name  string
+		rule  Rule
+		value string
+		want  string
+	}{
+		{name: "length ok", rule: Length(2, 4), value: "abc"},
+		{name: "length counts runes", rule: Length(2, 4), value: "ãéíõ"},
+		{name: "too short", rule: Length(2, 4), value: "a", want: "must be between 2 and 4 characters"},
+		{name: "too long", rule: Length(2, 4), value: "abcde", want: "must be between 2 and 4 characters"},
+		{name: "max length ok", rule: MaxLength(3), value: "ção"},
+		{name: "max length", rule: MaxLength(3), value: "abcd", want: "must be at most 3 characters"},
+		{name: "pattern ok", rule: Pattern(regexp.MustCompile(`^\d+$`), "digits only"), value: "123"},
+		{name: "pattern", rule: Pattern(regexp.MustCompile(`^\d+$`), "digits only"), value: "12a", want: "digits only"},
+		{name: "one of ok", rule: OneOf("public", "private"), value: "private"},
+		{name: "one of", rule: OneOf("public", "private"), value: "Public", want: "must be one of public, private"},
+
+		{name: "e-mail", rule: Email, value: "ana@example.com"},
+		{name: "e-mail with a display name", rule: Email, value: "Ana <ana@example.com>", want: "must be a valid e-mail address"},
+		{name: "not an e-mail", rule: Email, value: "ana.example.com", want: "must be a valid e-mail address"},
+
+		{name: "strId", rule: StrID, value: "ana.b-c_1"},
+		{name: "strId with accents", rule: StrID, value: "joão"},
+		{name: "strId too short", rule: StrID, value: "ab", want: "must be between 3 and 30 characters"},
+		{name: "strId ending in a dot", rule: StrID, value: "ana.", want: "may only contain letters, digits, underscores, dots and dashes, and must start and end with a letter, digit or underscore"},
+		{name: "strId with a space", rule: StrID, value: "ana b", want: "may only contain letters, digits, underscores, dots and dashes, and must start and end with a letter, digit or underscore"},
+		{name: "strId with an at sign", rule: StrID, value: "@ana", want: "may only contain letters, digits, underscores, dots and dashes, and must start and end with a letter, digit or underscore"},
+
+		{name: "password", rule: Password, value: "secret123"},
+		{name: "password too short", rule: Password, value: "abc123", want: "must be at least 8 characters"},
+		{name: "password over 72 bytes", rule: Password, value: strings.Repeat("é", 36) + "1", want: "must be at most 72 bytes"},
+		{name: "password without a digit", rule: Password, value: "password", want: "must contain at least one letter and one digit"},
+		{name: "password without a letter", rule: Password, value: "12345678", want: "must contain at least one letter and one digit"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rule(tt.value); got != tt.want {
+				t.Errorf("rule(%q) = %q, want %q", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidator(t *testing.T) {
+	tests := []struct {
+		name  string
+		check func(v *Validator)
+		want  []apierror.FieldError
+	}{
+		{
+			name: "valid",
+			check: func(v *Validator) {
+				v.Required("name", "Ana", MaxLength(10))
+				v.Optional("description", "")
+				v.List("subjects", []string{"go"}, 2, MaxLength(5))
+			},
+		},
+		{
+			name: "required and blank",
+			check: func(v *Validator) {
+				v.Required("name", "  ", MaxLength(1))
+			},
+			want: []apierror.FieldError{{Field: "name", Message: "is required"}},
+		},
+		{
+			name: "only the first failing rule is reported",
+			check: func(v *Validator) {
+				v.Required("strId", "a!", Length(3, 30), StrID)
+			},
+			want: []apierror.FieldError{{Field: "strId", Message: "must be between 3 and 30 characters"}},
+		},
+		{
+			name: "optional given",
+			check: func(v *Validator) {
+				v.Optional("email", "nope", Email)
+			},
+			want: []apierror.FieldError{{Field: "email", Message: "must be a valid e-mail address"}},
+		},
+		{
+			name: "list too long",
+			check: func(v *Validator) {
+				v.List("subjects", []string{"a", "b", "c"}, 2)
+			},
+			want: []apierror.FieldError{{Field: "subjects", Message: "must have at most 2 values"}},
+		},
+		{
+			name: "list values",
+			check: func(v *Validator) {
+				v.List("subjects", []string{"go", " ", "toolong"}, 5, MaxLength(5))
+			},
+			want: []apierror.FieldError{
+				{Field: "subjects[1]", Message: "must not be blank"},
+				{Field: "subjects[2]", Message: "must be at most 5 characters"},
+			},
+		},
+		{
+			name: "every field is collected",
+			check: func(v *Validator) {
+				v.Required("name", "")
+				v.Add("visibility", "is not allowed here")
+			},
+			want: []apierror.FieldError{
+				{Field: "name", Message: "is required"},
+				{Field: "visibility", Message: "is not allowed here"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			v := New()
+			tt.check(v)
+
+			err := v.Err()
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Errorf("Err() = %+v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Err() = nil, want %+v", tt.want)
+			}
+			if !reflect.DeepEqual(err.Fields, tt.want) {
+				t.Errorf("Err().Fields = %+v, want %+v", err.Fields, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+// Fills in the fields added to existing collections since the first schema.
+// Prisma only applies @default when it creates a document, and MongoDB
+// filters and sorts do not treat a missing field as its default, so older
+// documents would otherwise drop out of lists and orderings.
+//
+// The script only sets missing fields and can be run again safely:
+//
+//   mongosh "$DATABASE_URL" scripts/migrations/backfill-defaults.js
+
+// cuid() ids start with "c" and the creation time in milliseconds in base
+// 36, which is the best guess for records that never stored it.
+function createdAtOf(id) {
+  var millis = typeof id === "string" && id.charAt(0) === "c" ? parseInt(id.substr(1, 8), 36) : NaN;
+  return isNaN(millis) ? new Date() : new Date(millis);
+}
+
+function setMissing(collection, field, value) {
+  var filter = {};
+  filter[field] = { $exists: false };
+  var update = {};
+  update[field] = value;
+
+  var result = db.getCollection(collection).updateMany(filter, { $set: update });
+  print(collection + "." + field + ": " + result.modifiedCount);
+}
+
+function setMissingCreatedAt(collection) {
+  var count = 0;
+  db.getCollection(collection).find({ createdAt: { $exists: false } }, { _id: 1 }).forEach(function (doc) {
+    db.getCollection(collection).updateOne({ _id: doc._id }, { $set: { createdAt: createdAtOf(doc._id) } });
+    count++;
+  });
+  print(collection + ".createdAt: " + count);
+}
+
+setMissingCreatedAt("Media");
+
+// followerCount is kept in step with Follow by the follow handlers, so it
+// starts from what is there.
+var followers = 0;
+db.getCollection("User").find({ followerCount: { $exists: false } }, { _id: 1 }).forEach(function (user) {
+  var count = db.getCollection("Follow").countDocuments({ followingId: user._id });
+  db.getCollection("User").updateOne({ _id: user._id }, { $set: { followerCount: count } });
+  followers++;
+});
+print("User.followerCount: " + followers);
+setMissingCreatedAt("Follow");
+
+setMissingCreatedAt("Like");
+setMissingCreatedAt("Dislike");
+setMissingCreatedAt("Comment");
+
+setMissing("User", "historyPaused", false);
+setMissing("Media", "viewCount", 0);
+setMissing("Media", "watchTime", 0);
+
+setMissing("Media", "duration", 0);
+
+setMissing("User", "role", "user");
+setMissing("User", "hidden", false);
+setMissing("Media", "hidden", false);
+setMissing("Comment", "hidden", false);
+
+setMissing("User", "isPrivate", false);
+
+setMissing("Media", "hashtags", []);
+
+// Only one Watch later playlist per user may keep the system key. Extra
+// ones, left by concurrent first requests, become custom playlists so
+// nothing saved in them is lost.
+var systemKeys = 0;
+db.getCollection("Playlist").find({ systemKey: { $exists: false } }).sort({ createdAt: 1 }).forEach(function (playlist) {
+  var key = String(playlist._id);
+  var update = {};
+  if (playlist.kind === "watch_later") {
+    if (db.getCollection("Playlist").countDocuments({ userId: playlist.userId, systemKey: "watch_later" }) === 0) {
+      key = "watch_later";
+    } else {
+      update.kind = "custom";
+    }
+  }
+  update.systemKey = key;
+  db.getCollection("Playlist").updateOne({ _id: playlist._id }, { $set: update });
+  systemKeys++;
+});
+print("Playlist.systemKey: " + systemKeys);